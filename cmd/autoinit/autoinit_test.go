@@ -8,12 +8,13 @@ import (
 	"testing"
 
 	"github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/internal/testutil"
 	"github.com/spf13/cobra"
 )
 
 func TestHasCompleteLocalAppConfig(t *testing.T) {
 	t.Run("returns_false_when_local_config_is_missing", func(t *testing.T) {
-		withTempDir(t)
+		testutil.ChdirTemp(t)
 
 		complete, err := HasCompleteLocalAppConfig()
 
@@ -26,7 +27,7 @@ func TestHasCompleteLocalAppConfig(t *testing.T) {
 	})
 
 	t.Run("returns_false_when_required_app_fields_are_missing", func(t *testing.T) {
-		dir := withTempDir(t)
+		dir := testutil.ChdirTemp(t)
 		writeLocalConfig(t, dir, `{
   "organization_id": "org_test",
   "project_id": "proj_test",
@@ -44,7 +45,7 @@ func TestHasCompleteLocalAppConfig(t *testing.T) {
 	})
 
 	t.Run("returns_true_when_local_app_config_is_complete", func(t *testing.T) {
-		dir := withTempDir(t)
+		dir := testutil.ChdirTemp(t)
 		writeLocalConfig(t, dir, `{
   "organization_id": "org_test",
   "project_id": "proj_test",
@@ -125,7 +126,7 @@ func TestNeedsLocalAppConfig(t *testing.T) {
 
 func TestEnsure(t *testing.T) {
 	t.Run("does_nothing_when_command_does_not_need_local_app_config", func(t *testing.T) {
-		withTempDir(t)
+		testutil.ChdirTemp(t)
 		restore := stubAutoInit(t)
 		restore.runInitApp = func(cmd *cobra.Command, args []string) error {
 			t.Fatalf("runInitApp should not be called")
@@ -140,7 +141,7 @@ func TestEnsure(t *testing.T) {
 	})
 
 	t.Run("does_nothing_when_local_app_config_is_complete", func(t *testing.T) {
-		dir := withTempDir(t)
+		dir := testutil.ChdirTemp(t)
 		writeCompleteLocalConfig(t, dir)
 		restore := stubAutoInit(t)
 		restore.runInitApp = func(cmd *cobra.Command, args []string) error {
@@ -156,7 +157,7 @@ func TestEnsure(t *testing.T) {
 	})
 
 	t.Run("fails_with_guidance_when_noninteractive", func(t *testing.T) {
-		withTempDir(t)
+		testutil.ChdirTemp(t)
 		restore := stubAutoInit(t)
 		restore.isStdinTerminal = func() bool { return false }
 		restore.ensureAuthenticated = func() error {
@@ -179,7 +180,7 @@ func TestEnsure(t *testing.T) {
 	})
 
 	t.Run("runs_init_noninteractively_when_yes_flag_is_set", func(t *testing.T) {
-		dir := withTempDir(t)
+		dir := testutil.ChdirTemp(t)
 		restore := stubAutoInit(t)
 		restore.isStdinTerminal = func() bool { return false }
 		called := false
@@ -203,7 +204,7 @@ func TestEnsure(t *testing.T) {
 	})
 
 	t.Run("fails_with_guidance_when_no_flag_is_set", func(t *testing.T) {
-		withTempDir(t)
+		testutil.ChdirTemp(t)
 		restore := stubAutoInit(t)
 		restore.runInitApp = func(cmd *cobra.Command, args []string) error {
 			t.Fatalf("runInitApp should not be called")
@@ -224,7 +225,7 @@ func TestEnsure(t *testing.T) {
 	})
 
 	t.Run("fails_with_guidance_for_json_output", func(t *testing.T) {
-		withTempDir(t)
+		testutil.ChdirTemp(t)
 		restore := stubAutoInit(t)
 		restore.runInitApp = func(cmd *cobra.Command, args []string) error {
 			t.Fatalf("runInitApp should not be called")
@@ -245,7 +246,7 @@ func TestEnsure(t *testing.T) {
 	})
 
 	t.Run("runs_init_and_revalidates_before_continuing", func(t *testing.T) {
-		dir := withTempDir(t)
+		dir := testutil.ChdirTemp(t)
 		restore := stubAutoInit(t)
 		called := false
 		restore.runInitApp = func(cmd *cobra.Command, args []string) error {
@@ -268,7 +269,7 @@ func TestEnsure(t *testing.T) {
 	})
 
 	t.Run("returns_init_error", func(t *testing.T) {
-		withTempDir(t)
+		testutil.ChdirTemp(t)
 		restore := stubAutoInit(t)
 		restore.runInitApp = func(cmd *cobra.Command, args []string) error {
 			return errors.New("init failed")
@@ -328,28 +329,6 @@ func commandForPath(parts ...string) *cobra.Command {
 	return current
 }
 
-func withTempDir(t *testing.T) string {
-	t.Helper()
-
-	originalDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("failed to get current directory: %v", err)
-	}
-
-	dir := t.TempDir()
-	if err := os.Chdir(dir); err != nil {
-		t.Fatalf("failed to chdir to temp dir: %v", err)
-	}
-
-	t.Cleanup(func() {
-		if err := os.Chdir(originalDir); err != nil {
-			t.Fatalf("failed to restore current directory: %v", err)
-		}
-	})
-
-	return dir
-}
-
 func writeCompleteLocalConfig(t *testing.T, dir string) {
 	t.Helper()
 	writeLocalConfig(t, dir, `{
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/Hyphen/cli/internal/build"
+	"github.com/Hyphen/cli/internal/config"
 	hyphenapp "github.com/Hyphen/cli/internal/hyphenApp"
 	"github.com/Hyphen/cli/internal/user"
 	"github.com/Hyphen/cli/pkg/cprint"
@@ -74,7 +75,7 @@ func emitFailure(p *cprint.CPrinter, partial map[string]any, err error) {
 // --output=json is set.
 func runBuild(cmd *cobra.Command) (map[string]any, error) {
 	service := build.NewService()
-	result, err := service.RunBuild(cmd, printer, flags.EnvironmentFlag, flags.VerboseFlag, flags.DockerfileFlag, flags.PreviewNameFlag)
+	result, err := service.RunBuild(cmd, printer, flags.EnvironmentFlag, flags.VerboseFlag, config.ResolveInvocationPath(flags.DockerfileFlag), flags.PreviewNameFlag)
 	if err != nil {
 		return nil, err
 	}
@@ -15,8 +15,14 @@ var printer *cprint.CPrinter
 var ConfigCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Manage Hyphen CLI configuration",
-	Long:  `Manage global Hyphen CLI configuration stored on disk.`,
-	Args:  cobra.NoArgs,
+	Long: `
+Manage Hyphen CLI configuration stored on disk.
+
+Settings live in two .hx files: the global one of the current context and the
+project's local one, whose values take precedence. Use --global or --local to
+read or write just one of them.
+`,
+	Args: cobra.NoArgs,
 }
 
 var AutoUpdateCmd = &cobra.Command{
@@ -59,4 +65,16 @@ func parseAutoUpdateEnabledArg(value string) (bool, error) {
 
 func init() {
 	ConfigCmd.AddCommand(AutoUpdateCmd)
+	ConfigCmd.AddCommand(GetCmd)
+	ConfigCmd.AddCommand(SetCmd)
+	ConfigCmd.AddCommand(UnsetCmd)
+	ConfigCmd.AddCommand(ListCmd)
+
+	for _, cmd := range []*cobra.Command{GetCmd, SetCmd, UnsetCmd, ListCmd} {
+		cmd.Flags().BoolVar(&globalFlag, "global", false, "Use the global .hx of the current context")
+		cmd.Flags().BoolVar(&localFlag, "local", false, "Use the project's .hx in the current directory")
+		cmd.MarkFlagsMutuallyExclusive("global", "local")
+	}
+	GetCmd.Flags().BoolVar(&showOriginFlag, "show-origin", false, "Also show which file the value comes from")
+	ListCmd.Flags().BoolVar(&allFlag, "all", false, "Also list keys that aren't set")
 }
@@ -0,0 +1,267 @@
+package configcmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	internalconfig "github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	globalFlag     bool
+	localFlag      bool
+	showOriginFlag bool
+	allFlag        bool
+)
+
+var GetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print the value of a config key",
+	Long: `
+Print the effective value of a config key: the project's local value if it has
+one, otherwise the global value. Use --show-origin to see which file it came
+from.
+
+Examples:
+  hyphen config get project_id
+  hyphen config get organization_id --show-origin
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return runGet(args[0])
+	},
+}
+
+var SetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a config key",
+	Long: `
+Set a config key. Without --global or --local the value goes to the project's
+.hx if there is one in the current directory, and to the global .hx otherwise.
+Credentials and API endpoints can only be set globally.
+
+Examples:
+  hyphen config set project_id proj_123
+  hyphen config set endpoints.api https://dev-api.hyphen.ai --global
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return runSet(args[0], args[1])
+	},
+}
+
+var UnsetCmd = &cobra.Command{
+	Use:   "unset KEY",
+	Short: "Remove a config key",
+	Long: `
+Remove a config key. Without --global or --local it is removed from the file
+the effective value comes from.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return runUnset(args[0])
+	},
+}
+
+var ListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List config keys with their values and origins",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return runList()
+	},
+}
+
+// files holds both .hx files, read separately so every value can be traced
+// back to the file it came from.
+type files struct {
+	global internalconfig.Config
+	local  internalconfig.Config
+}
+
+func readFiles() (files, error) {
+	global, err := internalconfig.ReadConfigFile(internalconfig.ScopeGlobal)
+	if err != nil {
+		return files{}, err
+	}
+	local, err := internalconfig.ReadConfigFile(internalconfig.ScopeLocal)
+	if err != nil {
+		return files{}, err
+	}
+	return files{global: global, local: local}, nil
+}
+
+// lookup returns a key's value in the requested scope and the scope it was
+// found in. With no scope the local value wins, except for global-only keys,
+// which are never read from a project's .hx.
+func (f files) lookup(k internalconfig.Key, scope internalconfig.Scope) (string, internalconfig.Scope, bool) {
+	if scope != internalconfig.ScopeGlobal && k.Scope != internalconfig.ScopeGlobal {
+		if v, ok := k.Get(f.local); ok {
+			return v, internalconfig.ScopeLocal, true
+		}
+	}
+	if scope != internalconfig.ScopeLocal {
+		if v, ok := k.Get(f.global); ok {
+			return v, internalconfig.ScopeGlobal, true
+		}
+	}
+	return "", internalconfig.ScopeAny, false
+}
+
+func requestedScope() internalconfig.Scope {
+	switch {
+	case globalFlag:
+		return internalconfig.ScopeGlobal
+	case localFlag:
+		return internalconfig.ScopeLocal
+	default:
+		return internalconfig.ScopeAny
+	}
+}
+
+// origin describes the file of a scope, such as "global (/home/me/.hx,
+// context default)".
+func origin(scope internalconfig.Scope) string {
+	path, err := internalconfig.ConfigFile(scope)
+	if err != nil {
+		return scope.String()
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if scope == internalconfig.ScopeGlobal {
+		return fmt.Sprintf("global (%s, context %s)", path, internalconfig.ActiveContext())
+	}
+	return fmt.Sprintf("local (%s)", path)
+}
+
+func display(k internalconfig.Key, value string) string {
+	if k.Secret {
+		return env.MaskValue(value)
+	}
+	return value
+}
+
+func runGet(name string) error {
+	k, err := internalconfig.LookupKey(name)
+	if err != nil {
+		return err
+	}
+	f, err := readFiles()
+	if err != nil {
+		return err
+	}
+
+	value, scope, ok := f.lookup(k, requestedScope())
+	if !ok {
+		return fmt.Errorf("%s is not set", name)
+	}
+	if showOriginFlag {
+		printer.PrintNorm(fmt.Sprintf("%s\t%s", display(k, value), origin(scope)))
+		return nil
+	}
+	printer.PrintNorm(display(k, value))
+	return nil
+}
+
+func runSet(name, value string) error {
+	k, err := internalconfig.LookupKey(name)
+	if err != nil {
+		return err
+	}
+
+	scope := requestedScope()
+	if k.Scope != internalconfig.ScopeAny {
+		if scope != internalconfig.ScopeAny && scope != k.Scope {
+			return fmt.Errorf("%s can only be set in the %s config", name, k.Scope)
+		}
+		scope = k.Scope
+	}
+	if scope == internalconfig.ScopeAny {
+		scope = internalconfig.ScopeGlobal
+		if internalconfig.ExistsLocal() {
+			scope = internalconfig.ScopeLocal
+		}
+	}
+	if scope == internalconfig.ScopeLocal && !internalconfig.ExistsLocal() {
+		return fmt.Errorf("there is no .hx in the current directory; run `hx init` or use --global")
+	}
+
+	c, err := internalconfig.ReadConfigFile(scope)
+	if err != nil {
+		return err
+	}
+	if err := k.Set(&c, value); err != nil {
+		return err
+	}
+	if err := internalconfig.WriteConfigFile(scope, c); err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Set %s in %s", name, origin(scope)))
+	if scope == internalconfig.ScopeGlobal && k.Scope == internalconfig.ScopeAny {
+		if f, err := readFiles(); err == nil {
+			if _, from, ok := f.lookup(k, internalconfig.ScopeAny); ok && from == internalconfig.ScopeLocal {
+				printer.Warning(fmt.Sprintf("The project's .hx also sets %s, which takes precedence here.", name))
+			}
+		}
+	}
+	return nil
+}
+
+func runUnset(name string) error {
+	k, err := internalconfig.LookupKey(name)
+	if err != nil {
+		return err
+	}
+	f, err := readFiles()
+	if err != nil {
+		return err
+	}
+
+	_, scope, ok := f.lookup(k, requestedScope())
+	if !ok {
+		printer.Info(fmt.Sprintf("%s is not set", name))
+		return nil
+	}
+
+	c := f.global
+	if scope == internalconfig.ScopeLocal {
+		c = f.local
+	}
+	if err := k.Unset(&c); err != nil {
+		return err
+	}
+	if err := internalconfig.WriteConfigFile(scope, c); err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Removed %s from %s", name, origin(scope)))
+	return nil
+}
+
+func runList() error {
+	f, err := readFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, k := range internalconfig.Keys() {
+		value, scope, ok := f.lookup(k, requestedScope())
+		switch {
+		case ok:
+			printer.Print(fmt.Sprintf("%s = %s\t%s", k.Name, display(k, value), origin(scope)))
+		case allFlag:
+			printer.Print(fmt.Sprintf("%s\t(not set) %s", k.Name, k.Description))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package configcmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	internalconfig "github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/internal/testutil"
+	"github.com/Hyphen/cli/pkg/cprint"
+)
+
+func setupConfigFiles(t *testing.T, global, local string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(internalconfig.ContextEnv, "")
+	t.Setenv(internalconfig.ConfigDirEnv, filepath.Join(home, "hx"))
+
+	testutil.ChdirTemp(t)
+
+	globalDir, _, err := internalconfig.PrepareGlobalDirectory()
+	if err != nil {
+		t.Fatalf("prepare global directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(globalDir, ".hx"), []byte(global), 0o644); err != nil {
+		t.Fatalf("write global: %v", err)
+	}
+	if local != "" {
+		if err := os.WriteFile(".hx", []byte(local), 0o644); err != nil {
+			t.Fatalf("write local: %v", err)
+		}
+	}
+	globalFlag, localFlag = false, false
+	printer = cprint.NewCPrinter(false)
+}
+
+func TestLookupPrecedence(t *testing.T) {
+	setupConfigFiles(t,
+		`{"project_id": "proj_global", "organization_id": "org_global", "endpoints": {"api": "https://api.example.com"}}`,
+		`{"project_id": "proj_local", "endpoints": {"api": "https://evil.example.com"}}`)
+
+	f, err := readFiles()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		key       string
+		scope     internalconfig.Scope
+		wantValue string
+		wantScope internalconfig.Scope
+	}{
+		{key: "project_id", scope: internalconfig.ScopeAny, wantValue: "proj_local", wantScope: internalconfig.ScopeLocal},
+		{key: "project_id", scope: internalconfig.ScopeGlobal, wantValue: "proj_global", wantScope: internalconfig.ScopeGlobal},
+		{key: "organization_id", scope: internalconfig.ScopeAny, wantValue: "org_global", wantScope: internalconfig.ScopeGlobal},
+		{key: "endpoints.api", scope: internalconfig.ScopeAny, wantValue: "https://api.example.com", wantScope: internalconfig.ScopeGlobal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key+"_"+tc.scope.String(), func(t *testing.T) {
+			k, err := internalconfig.LookupKey(tc.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			value, scope, ok := f.lookup(k, tc.scope)
+			if !ok || value != tc.wantValue || scope != tc.wantScope {
+				t.Fatalf("expected %q from %s, got %q from %s", tc.wantValue, tc.wantScope, value, scope)
+			}
+		})
+	}
+}
+
+func TestSetAndUnset(t *testing.T) {
+	setupConfigFiles(t, `{"organization_id": "org_global"}`, `{"app_id": "app_1"}`)
+
+	if err := runSet("project_id", "proj_123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	local, err := internalconfig.ReadConfigFile(internalconfig.ScopeLocal)
+	if err != nil || local.ProjectId == nil || *local.ProjectId != "proj_123" || local.AppId == nil {
+		t.Fatalf("expected project_id in the local config alongside app_id, got %+v (%v)", local, err)
+	}
+
+	localFlag = true
+	if err := runSet("endpoints.api", "https://api.example.com"); err == nil {
+		t.Fatal("expected an error setting an endpoint locally")
+	}
+	localFlag = false
+
+	if err := runUnset("organization_id"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	global, err := internalconfig.ReadConfigFile(internalconfig.ScopeGlobal)
+	if err != nil || global.OrganizationId != "" {
+		t.Fatalf("expected organization_id to be removed from the global config, got %+v (%v)", global, err)
+	}
+}
@@ -0,0 +1,173 @@
+package contextcmd
+
+import (
+	"fmt"
+
+	internalconfig "github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/Hyphen/cli/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var printer *cprint.CPrinter
+
+var endpoints internalconfig.Endpoints
+
+var ContextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage named configuration contexts",
+	Long: `
+Contexts keep separate credentials, organization, default project and API
+endpoints, for example for a personal organization, a client organization and
+a development deployment.
+
+The default context is your global .hx file. Every command uses the current
+context (see 'hx context use') unless another one is picked with --context or
+the HX_CONTEXT environment variable.
+`,
+	Args: cobra.NoArgs,
+}
+
+var ListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configuration contexts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return runList()
+	},
+}
+
+var CreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create a configuration context",
+	Long: `
+Create a named context. Pass --organization and --project to set its defaults
+and the --*-url flags to point it at other API endpoints, then switch to it
+with 'hx context use NAME' and sign in with 'hx auth'.
+
+Examples:
+  hyphen context create client --organization org_123
+  hyphen context create dev --api-url https://dev-api.hyphen.ai --auth-url https://dev-auth.hyphen.ai
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return runCreate(args[0])
+	},
+}
+
+var UseCmd = &cobra.Command{
+	Use:   "use NAME",
+	Short: "Switch the current configuration context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		if err := internalconfig.UseContext(args[0]); err != nil {
+			return err
+		}
+		printer.Success(fmt.Sprintf("Switched to context '%s'", args[0]))
+		return nil
+	},
+}
+
+var DeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Delete a configuration context and its credentials",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return runDelete(cmd, args[0])
+	},
+}
+
+func init() {
+	CreateCmd.Flags().StringVar(&endpoints.Api, "api-url", "", "Base URL of the Hyphen API")
+	CreateCmd.Flags().StringVar(&endpoints.Horizon, "horizon-url", "", "Base URL of Horizon")
+	CreateCmd.Flags().StringVar(&endpoints.App, "app-url", "", "Base URL of the Hyphen app")
+	CreateCmd.Flags().StringVar(&endpoints.Auth, "auth-url", "", "Base URL of Hyphen authentication")
+	CreateCmd.Flags().StringVar(&endpoints.Vinz, "vinz-url", "", "Base URL of Hyphen's key store")
+
+	ContextCmd.AddCommand(ListCmd)
+	ContextCmd.AddCommand(CreateCmd)
+	ContextCmd.AddCommand(UseCmd)
+	ContextCmd.AddCommand(DeleteCmd)
+}
+
+func runList() error {
+	names, err := internalconfig.ListContexts()
+	if err != nil {
+		return err
+	}
+	active := internalconfig.ActiveContext()
+
+	for _, name := range names {
+		marker := "  "
+		if name == active {
+			marker = "* "
+		}
+		line := marker + name
+		if c, err := internalconfig.RestoreContextConfig(name); err == nil {
+			line += describeContext(c)
+		}
+		printer.Print(line)
+	}
+	return nil
+}
+
+func describeContext(c internalconfig.Config) string {
+	description := ""
+	if c.OrganizationId != "" {
+		description += fmt.Sprintf("  organization %s", c.OrganizationId)
+	}
+	if c.ProjectId != nil {
+		description += fmt.Sprintf("  project %s", *c.ProjectId)
+	}
+	if c.Endpoints != nil && c.Endpoints.Api != "" {
+		description += fmt.Sprintf("  api %s", c.Endpoints.Api)
+	}
+	if c.HyphenAccessToken == nil && c.HyphenAPIKey == nil {
+		description += "  (not signed in)"
+	}
+	return description
+}
+
+func runCreate(name string) error {
+	mc := internalconfig.Config{
+		OrganizationId: flags.OrganizationFlag,
+	}
+	if flags.ProjectFlag != "" {
+		mc.ProjectId = &flags.ProjectFlag
+	}
+	if endpoints != (internalconfig.Endpoints{}) {
+		e := endpoints
+		mc.Endpoints = &e
+	}
+
+	if err := internalconfig.CreateContext(name, mc); err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Created context '%s'", name))
+	printer.Info(fmt.Sprintf("Switch to it with `hx context use %s` and sign in with `hx auth`.", name))
+	return nil
+}
+
+func runDelete(cmd *cobra.Command, name string) error {
+	if !internalconfig.ContextExists(name) {
+		return fmt.Errorf("context '%s' does not exist", name)
+	}
+
+	response := prompt.PromptYesNo(cmd, fmt.Sprintf("Delete context '%s' and its credentials?", name), false)
+	if !response.Confirmed {
+		printer.Info("Delete cancelled")
+		return nil
+	}
+
+	if err := internalconfig.DeleteContext(name); err != nil {
+		return err
+	}
+	printer.Success(fmt.Sprintf("Deleted context '%s'", name))
+	return nil
+}
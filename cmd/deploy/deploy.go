@@ -281,7 +281,7 @@ func runDeployBody(cmd *cobra.Command, args []string) (map[string]any, error) {
 
 			if pa.BuildSpec == "" && matchesHxApp(pa.ID, cfg) {
 				buildSvc := build.NewService()
-				buildResult, err := buildSvc.RunBuild(cmd, printer, selectedDeployment.ProjectEnvironment.ID, flags.VerboseFlag, flags.DockerfileFlag, flags.PreviewNameFlag)
+				buildResult, err := buildSvc.RunBuild(cmd, printer, selectedDeployment.ProjectEnvironment.ID, flags.VerboseFlag, config.ResolveInvocationPath(flags.DockerfileFlag), flags.PreviewNameFlag)
 				if err != nil {
 					return result, err
 				}
@@ -316,7 +316,7 @@ func runDeployBody(cmd *cobra.Command, args []string) (map[string]any, error) {
 		}
 	} else {
 		buildSvc := build.NewService()
-		buildResult, err := buildSvc.RunBuild(cmd, printer, selectedDeployment.ProjectEnvironment.ID, flags.VerboseFlag, flags.DockerfileFlag, flags.PreviewNameFlag)
+		buildResult, err := buildSvc.RunBuild(cmd, printer, selectedDeployment.ProjectEnvironment.ID, flags.VerboseFlag, config.ResolveInvocationPath(flags.DockerfileFlag), flags.PreviewNameFlag)
 		if err != nil {
 			return result, err
 		}
@@ -0,0 +1,180 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	version        int
+	againstVersion int
+	againstEnv     string
+	showValues     bool
+	printer        *cprint.CPrinter
+)
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff [environment]",
+	Short: "Show key-level differences for an environment",
+	Long: `
+The diff command decrypts a remote environment and compares it key by key with
+another copy of the same variables.
+
+By default the remote environment is compared with your local .env file for it.
+You can instead compare it with another remote version or another environment:
+--version: Remote version to use as the base of the comparison (default: latest)
+--against-version: Compare with this remote version of the same environment
+--against-env: Compare with the latest version of another environment
+--show-values: Print values instead of masking them
+
+Each key is reported as added (+), removed (-) or changed (~), going from the
+remote base to the compared copy.
+
+Examples:
+  hyphen env diff production
+  hyphen env diff production --version 3
+  hyphen env diff production --against-version 3
+  hyphen env diff staging --against-env production --show-values
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+
+		envName := "default"
+		if len(args) == 1 {
+			envName = args[0]
+		}
+		return RunDiff(envName)
+	},
+}
+
+func init() {
+	DiffCmd.Flags().IntVar(&version, "version", 0, "Remote version to compare from (default: latest)")
+	DiffCmd.Flags().IntVar(&againstVersion, "against-version", 0, "Compare with another remote version of the environment")
+	DiffCmd.Flags().StringVar(&againstEnv, "against-env", "", "Compare with the latest version of another environment")
+	DiffCmd.Flags().BoolVar(&showValues, "show-values", false, "Show values instead of masking them")
+}
+
+func RunDiff(envName string) error {
+	if againstEnv != "" && againstVersion != 0 {
+		return errors.New("Use either --against-env or --against-version, not both")
+	}
+
+	envName, err := env.GetEnvName(envName)
+	if err != nil {
+		return err
+	}
+
+	remote, err := env.NewRemote(env.NewService())
+	if err != nil {
+		return err
+	}
+
+	var versionPtr *int
+	if version != 0 {
+		versionPtr = &version
+	}
+
+	base, baseLabel, err := remoteVariables(remote, envName, versionPtr)
+	if err != nil {
+		return err
+	}
+
+	var target map[string]string
+	var targetLabel string
+	switch {
+	case againstEnv != "":
+		otherEnv, err := env.GetEnvName(againstEnv)
+		if err != nil {
+			return err
+		}
+		target, targetLabel, err = remoteVariables(remote, otherEnv, nil)
+		if err != nil {
+			return err
+		}
+	case againstVersion != 0:
+		target, targetLabel, err = remoteVariables(remote, envName, &againstVersion)
+		if err != nil {
+			return err
+		}
+	default:
+		target, targetLabel, err = localVariables(envName)
+		if err != nil {
+			return err
+		}
+	}
+
+	printDiff(baseLabel, targetLabel, env.DiffVariables(base, target))
+	return nil
+}
+
+func remoteVariables(remote *env.Remote, envName string, version *int) (map[string]string, string, error) {
+	r, err := remote.Fetch(envName, version)
+	if err != nil {
+		return nil, "", err
+	}
+	return variables(r.Content, r.Label(envName))
+}
+
+func localVariables(envName string) (map[string]string, string, error) {
+	content, label, err := env.LoadLocal(envName)
+	if err != nil {
+		return nil, "", err
+	}
+	return variables(content, label)
+}
+
+func variables(content, label string) (map[string]string, string, error) {
+	vars, err := env.Variables(content)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s is not a valid .env file: %w", label, err)
+	}
+	return vars, label, nil
+}
+
+func displayValue(value string) string {
+	if showValues {
+		return value
+	}
+	return env.MaskValue(value)
+}
+
+func printDiff(baseLabel, targetLabel string, changes []env.VariableChange) {
+	printer.Print(fmt.Sprintf("Comparing %s -> %s", baseLabel, targetLabel))
+
+	if len(changes) == 0 {
+		printer.Success("No differences")
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	var added, removed, changed int
+	for _, c := range changes {
+		switch c.Type {
+		case env.ChangeAdded:
+			added++
+			printer.PrintNorm(green(fmt.Sprintf("  + %s=%s", c.Key, displayValue(c.NewValue))))
+		case env.ChangeRemoved:
+			removed++
+			printer.PrintNorm(red(fmt.Sprintf("  - %s=%s", c.Key, displayValue(c.OldValue))))
+		case env.ChangeChanged:
+			changed++
+			if showValues {
+				printer.PrintNorm(yellow(fmt.Sprintf("  ~ %s: %s -> %s", c.Key, c.OldValue, c.NewValue)))
+			} else {
+				printer.PrintNorm(yellow(fmt.Sprintf("  ~ %s", c.Key)))
+			}
+		}
+	}
+
+	printer.Info(fmt.Sprintf("%d added, %d removed, %d changed", added, removed, changed))
+}
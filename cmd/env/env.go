@@ -1,12 +1,17 @@
 package env
 
 import (
+	"github.com/Hyphen/cli/cmd/env/diff"
+	"github.com/Hyphen/cli/cmd/env/export"
+	"github.com/Hyphen/cli/cmd/env/key"
 	"github.com/Hyphen/cli/cmd/env/list"
 	"github.com/Hyphen/cli/cmd/env/listversions"
 	"github.com/Hyphen/cli/cmd/env/pull"
 	"github.com/Hyphen/cli/cmd/env/push"
 	"github.com/Hyphen/cli/cmd/env/rotatekey"
 	"github.com/Hyphen/cli/cmd/env/run"
+	"github.com/Hyphen/cli/cmd/env/validate"
+	"github.com/Hyphen/cli/cmd/env/variable"
 	"github.com/Hyphen/cli/internal/user"
 	"github.com/Hyphen/cli/pkg/flags"
 	"github.com/spf13/cobra"
@@ -33,4 +38,14 @@ func init() {
 	EnvCmd.AddCommand(list.ListCmd)
 	EnvCmd.AddCommand(listversions.ListVersionsCmd)
 	EnvCmd.AddCommand(rotatekey.RotateCmd)
+	EnvCmd.AddCommand(diff.DiffCmd)
+	EnvCmd.AddCommand(variable.SetCmd)
+	EnvCmd.AddCommand(variable.GetCmd)
+	EnvCmd.AddCommand(variable.UnsetCmd)
+	EnvCmd.AddCommand(export.ExportCmd)
+	EnvCmd.AddCommand(variable.ImportCmd)
+	EnvCmd.AddCommand(validate.ValidateCmd)
+	EnvCmd.AddCommand(variable.RollbackCmd)
+	EnvCmd.AddCommand(variable.PromoteCmd)
+	EnvCmd.AddCommand(key.KeyCmd)
 }
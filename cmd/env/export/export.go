@@ -0,0 +1,119 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	format       string
+	local        bool
+	version      int
+	resourceName string
+	outFile      string
+	printer      *cprint.CPrinter
+)
+
+var ExportCmd = &cobra.Command{
+	Use:   "export [environment] --format FORMAT",
+	Short: "Export an environment in a format other tools understand",
+	Long: fmt.Sprintf(`
+The export command decrypts an environment and renders it for another tool,
+writing the result to stdout or to a file.
+
+By default the latest remote version is exported; use --version for an older
+one or --local to export your local .env file instead. Values are exported as
+written, without expanding ${VAR} references.
+
+Formats: %s
+
+--name sets the metadata name of the k8s-secret and k8s-configmap manifests
+(default: the environment name).
+
+Examples:
+  hyphen env export production --format k8s-secret --name api-env | kubectl apply -f -
+  hyphen env export staging --format github-actions >> "$GITHUB_ENV"
+  hyphen env export --local --format json --out env.json
+`, strings.Join(env.ExportFormats, ", ")),
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+
+		envName := "default"
+		if len(args) == 1 {
+			envName = args[0]
+		}
+		return RunExport(envName)
+	},
+}
+
+func init() {
+	ExportCmd.Flags().StringVar(&format, "format", "", fmt.Sprintf("Output format (%s)", strings.Join(env.ExportFormats, ", ")))
+	ExportCmd.Flags().BoolVar(&local, "local", false, "Export the local .env file instead of the remote environment")
+	ExportCmd.Flags().IntVar(&version, "version", 0, "Remote version to export (default: latest)")
+	ExportCmd.Flags().StringVar(&resourceName, "name", "", "Resource name for Kubernetes formats (default: the environment name)")
+	ExportCmd.Flags().StringVar(&outFile, "out", "", "Write to this file instead of stdout")
+}
+
+func RunExport(envName string) error {
+	if format == "" {
+		return fmt.Errorf("--format is required, expected one of: %s", strings.Join(env.ExportFormats, ", "))
+	}
+	if local && version != 0 {
+		return errors.New("Use either --local or --version, not both")
+	}
+
+	envName, err := env.GetEnvName(envName)
+	if err != nil {
+		return err
+	}
+
+	var content, label string
+	if local {
+		content, label, err = env.LoadLocal(envName)
+	} else {
+		var versionPtr *int
+		if version != 0 {
+			versionPtr = &version
+		}
+		var remote env.RemoteEnv
+		remote, err = env.LoadRemote(envName, versionPtr)
+		content, label = remote.Content, remote.Label(envName)
+	}
+	if err != nil {
+		return err
+	}
+
+	defs, err := env.Parse(content)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid .env file: %w", label, err)
+	}
+
+	name := resourceName
+	if name == "" {
+		name = envName
+	}
+	rendered, err := env.Render(format, env.Effective(defs), name)
+	if err != nil {
+		return err
+	}
+
+	if outFile == "" {
+		fmt.Print(rendered)
+		return nil
+	}
+
+	if err := os.WriteFile(config.ResolveInvocationPath(outFile), []byte(rendered), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outFile, err)
+	}
+	printer.Success(fmt.Sprintf("Exported %s as %s to %s", label, format, outFile))
+	return nil
+}
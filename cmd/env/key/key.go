@@ -0,0 +1,26 @@
+package key
+
+import (
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/spf13/cobra"
+)
+
+var printer *cprint.CPrinter
+
+var KeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage the project's secret key",
+	Long: `
+Manage the secret key that encrypts the project's environments.
+
+A key kept in a local .hxkey file (see --localSecret) can be locked with a
+passphrase, so it is never stored in plain text. Commands that need a locked
+key read the passphrase from HX_KEY_PASSPHRASE or ask for it.
+`,
+}
+
+func init() {
+	KeyCmd.AddCommand(LockCmd)
+	KeyCmd.AddCommand(UnlockCmd)
+	KeyCmd.AddCommand(MigrateCmd)
+}
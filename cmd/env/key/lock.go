@@ -0,0 +1,80 @@
+package key
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Hyphen/cli/internal/secret"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/Hyphen/cli/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var LockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Protect the local .hxkey with a passphrase",
+	Long: `
+The lock command encrypts the project's .hxkey with a passphrase, using a key
+derived with argon2id, and restricts the file to its owner.
+
+The passphrase is read from HX_KEY_PASSPHRASE, or asked for twice. From then on
+every command that needs the key reads the passphrase the same way. Earlier
+keys of the project in the local keyring are sealed with the same passphrase.
+
+Examples:
+  hyphen env key lock
+  HX_KEY_PASSPHRASE=... hyphen env key lock
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunLock(cmd)
+	},
+}
+
+func RunLock(cmd *cobra.Command) error {
+	passphrase := os.Getenv(secret.PassphraseEnv)
+	if passphrase == "" {
+		var err error
+		passphrase, err = prompt.PromptPassword(cmd, "New passphrase: ")
+		if err != nil {
+			return err
+		}
+		confirmation, err := prompt.PromptPassword(cmd, "Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if passphrase != confirmation {
+			return fmt.Errorf("passphrases don't match")
+		}
+	}
+
+	s, err := secret.LockLocalSecret(passphrase)
+	if err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Locked secret key %d in %s", s.SecretKeyId, secret.ManifestSecretFile))
+	sealKeyring(passphrase)
+	printer.Info(fmt.Sprintf("Keep the passphrase safe: without it the key, and the environments it encrypts, can't be recovered. Set %s to use the key non-interactively.", secret.PassphraseEnv))
+	return nil
+}
+
+// sealKeyring seals the project's earlier keys in the keyring, which would
+// otherwise stay readable next to the locked key.
+func sealKeyring(passphrase string) {
+	projectId, err := flags.GetProjectID()
+	if err != nil {
+		printer.Warning(fmt.Sprintf("Earlier secret keys in the keyring were not sealed: %s", err))
+		return
+	}
+	count, err := secret.SealKeyring(projectId, passphrase)
+	if err != nil {
+		printer.Warning(fmt.Sprintf("Earlier secret keys in the keyring were not sealed: %s", err))
+		return
+	}
+	if count > 0 {
+		printer.Info(fmt.Sprintf("Sealed %d earlier secret key(s) in the keyring", count))
+	}
+}
@@ -0,0 +1,199 @@
+package key
+
+import (
+	"fmt"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/internal/secret"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/Hyphen/cli/pkg/gitutil"
+	"github.com/Hyphen/cli/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateTo     string
+	migrateRemove bool
+)
+
+var MigrateCmd = &cobra.Command{
+	Use:   "migrate --to vinz|local",
+	Short: "Move the project's secret key between a local .hxkey and Hyphen's key store",
+	Long: `
+The migrate command moves the project's secret key between a local .hxkey file
+and Hyphen's secure key store (Vinz).
+
+Before the key is moved it must decrypt the latest version of every pushed
+environment of the project, so a stale or wrong key is never adopted.
+
+With --to vinz the local key is uploaded and the .hxkey is archived as
+.hxkey.migrated, or deleted with --remove. With --to local the key is written
+to .hxkey, locked if HX_KEY_PASSPHRASE is set. The key store has no way to
+delete a key, so it keeps its copy; use --localSecret to work with the local
+one.
+
+Examples:
+  hyphen env key migrate --to vinz
+  hyphen env key migrate --to vinz --remove
+  hyphen env key migrate --to local
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunMigrate(cmd)
+	},
+}
+
+func init() {
+	MigrateCmd.Flags().StringVar(&migrateTo, "to", "", "Where to store the key: vinz or local")
+	MigrateCmd.Flags().BoolVar(&migrateRemove, "remove", false, "Delete the local .hxkey instead of archiving it")
+	MigrateCmd.MarkFlagRequired("to")
+}
+
+type service struct {
+	envService env.EnvServicer
+}
+
+func newService(envService env.EnvServicer) *service {
+	return &service{
+		envService,
+	}
+}
+
+func RunMigrate(cmd *cobra.Command) error {
+	var from, to secret.SecretLocation
+	switch migrateTo {
+	case "vinz":
+		from, to = secret.SecretLocationLocal, secret.SecretLocationVinz
+	case "local":
+		from, to = secret.SecretLocationVinz, secret.SecretLocationLocal
+	default:
+		return fmt.Errorf("--to must be vinz or local, not '%s'", migrateTo)
+	}
+
+	organizationId, err := flags.GetOrganizationID()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get organization ID")
+	}
+	projectId, err := flags.GetProjectID()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get project ID")
+	}
+
+	key, found, err := secret.LoadSecretFrom(organizationId, projectId, from)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("there is no secret key %s to migrate", locationName(from))
+	}
+
+	existing, exists, err := secret.LoadSecretFrom(organizationId, projectId, to)
+	if err != nil {
+		return err
+	}
+	if exists && existing != key {
+		return fmt.Errorf("a different secret key (%d) is already stored %s; migrating would orphan one of them", existing.SecretKeyId, locationName(to))
+	}
+
+	appIds, err := flags.GetProjectApplicationIDs()
+	if err != nil {
+		return err
+	}
+	printer.Info(fmt.Sprintf("Checking that secret key %d decrypts every environment...", key.SecretKeyId))
+	count, err := newService(env.NewService()).verify(organizationId, appIds, key)
+	if err != nil {
+		return errors.Wrap(err, "The key was not migrated")
+	}
+	printer.Info(fmt.Sprintf("Secret key %d decrypts all %d environment(s)", key.SecretKeyId, count))
+
+	response := prompt.PromptYesNo(cmd, fmt.Sprintf("Store secret key %d %s?", key.SecretKeyId, locationName(to)), false)
+	if !response.Confirmed {
+		printer.Info("Migration cancelled")
+		return nil
+	}
+
+	if !exists {
+		if err := secret.SaveSecret(organizationId, projectId, to, key); err != nil {
+			return err
+		}
+		saved, found, err := secret.LoadSecretFrom(organizationId, projectId, to)
+		if err != nil {
+			return err
+		}
+		if !found || saved != key {
+			return fmt.Errorf("secret key %d could not be read back %s; the original was left in place", key.SecretKeyId, locationName(to))
+		}
+	}
+
+	if to == secret.SecretLocationLocal {
+		if err := gitutil.EnsureGitignore(secret.ManifestSecretFile); err != nil {
+			printer.Warning(fmt.Sprintf("Failed to add %s to .gitignore: %s", secret.ManifestSecretFile, err))
+		}
+		printer.Success(fmt.Sprintf("Secret key %d is stored in %s", key.SecretKeyId, secret.ManifestSecretFile))
+		printer.Info("Hyphen's key store keeps its copy of the key. Use --localSecret to work with the local one.")
+		return nil
+	}
+
+	archive, err := secret.ArchiveLocalSecret(migrateRemove)
+	if err != nil {
+		return err
+	}
+	printer.Success(fmt.Sprintf("Secret key %d is stored in Hyphen's key store", key.SecretKeyId))
+	if archive != "" {
+		_ = gitutil.EnsureGitignore(archive)
+		printer.Info(fmt.Sprintf("The local copy was archived as %s. Delete it once your team has switched over.", archive))
+	} else {
+		printer.Info(fmt.Sprintf("The local %s was deleted.", secret.ManifestSecretFile))
+	}
+	return nil
+}
+
+// verify checks that the latest version of every pushed environment of the
+// apps is encrypted with the key and decrypts with it. It returns how many
+// environments were checked.
+func (s *service) verify(orgId string, appIds []string, key models.Secret) (int, error) {
+	count := 0
+	for _, appId := range appIds {
+		pushed, err := env.ListAllEnvs(s.envService, orgId, appId)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list the environments of app %s: %w", appId, err)
+		}
+
+		for _, p := range pushed {
+			envName := "default"
+			if p.ProjectEnv != nil {
+				envName = p.ProjectEnv.AlternateID
+			}
+
+			e, err := s.envService.GetEnvironmentEnv(orgId, appId, envName, nil, nil)
+			if errors.Is(err, errors.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return 0, fmt.Errorf("failed to fetch the %s environment: %w", envName, err)
+			}
+
+			// The keyring would decrypt data of an earlier key too, so check
+			// which key the data says it was encrypted with first.
+			if id, ok := models.EncryptedSecretKeyID(e.Data); ok && id != key.SecretKeyId {
+				return 0, fmt.Errorf("the %s environment is encrypted with secret key %d, not %d", envName, id, key.SecretKeyId)
+			}
+			if _, err := key.Decrypt(e.Data); err != nil {
+				return 0, fmt.Errorf("the %s environment doesn't decrypt with secret key %d: %w", envName, key.SecretKeyId, err)
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+func locationName(location secret.SecretLocation) string {
+	if location == secret.SecretLocationLocal {
+		return "in " + secret.ManifestSecretFile
+	}
+	return "in Hyphen's key store"
+}
@@ -0,0 +1,58 @@
+package key
+
+import (
+	"testing"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/internal/testutil"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	key.SecretKeyId = 1
+	other, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	other.SecretKeyId = 2
+
+	setup := func(production models.Env) *service {
+		mockService := env.NewMockEnvService()
+		mockService.On("ListEnvs", "org-1", "app-1", 100, 1).Return([]models.Env{
+			{},
+			{ProjectEnv: &models.ProjectEnvironmentReference{AlternateID: "production"}},
+			{ProjectEnv: &models.ProjectEnvironmentReference{AlternateID: "deleted"}},
+		}, nil)
+		mockService.On("GetEnvironmentEnv", "org-1", "app-1", "default", mock.Anything, mock.Anything).Return(testutil.EncryptedEnv(t, key, "A=1\n", 1), nil)
+		mockService.On("GetEnvironmentEnv", "org-1", "app-1", "production", mock.Anything, mock.Anything).Return(production, nil)
+		mockService.On("GetEnvironmentEnv", "org-1", "app-1", "deleted", mock.Anything, mock.Anything).Return(models.Env{}, errors.Wrap(errors.ErrNotFound, "not found"))
+		return newService(mockService)
+	}
+
+	t.Run("counts_envs_encrypted_with_the_key", func(t *testing.T) {
+		count, err := setup(testutil.EncryptedEnv(t, key, "B=2\n", 1)).verify("org-1", []string{"app-1"}, key)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("rejects_envs_encrypted_with_another_key", func(t *testing.T) {
+		_, err := setup(testutil.EncryptedEnv(t, other, "B=2\n", 1)).verify("org-1", []string{"app-1"}, key)
+		assert.ErrorContains(t, err, "the production environment is encrypted with secret key 2, not 1")
+	})
+
+	t.Run("checks_every_page", func(t *testing.T) {
+		mockService := env.NewMockEnvService()
+		mockService.On("ListEnvs", "org-1", "app-1", 100, 1).Return(make([]models.Env, 100), nil)
+		mockService.On("ListEnvs", "org-1", "app-1", 100, 2).Return([]models.Env{
+			{ProjectEnv: &models.ProjectEnvironmentReference{AlternateID: "production"}},
+		}, nil)
+		mockService.On("GetEnvironmentEnv", "org-1", "app-1", "default", mock.Anything, mock.Anything).Return(testutil.EncryptedEnv(t, key, "A=1\n", 1), nil)
+		mockService.On("GetEnvironmentEnv", "org-1", "app-1", "production", mock.Anything, mock.Anything).Return(testutil.EncryptedEnv(t, other, "B=2\n", 1), nil)
+
+		_, err := newService(mockService).verify("org-1", []string{"app-1"}, key)
+		assert.ErrorContains(t, err, "the production environment is encrypted with secret key 2, not 1")
+	})
+}
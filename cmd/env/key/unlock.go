@@ -0,0 +1,50 @@
+package key
+
+import (
+	"fmt"
+
+	"github.com/Hyphen/cli/internal/secret"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/Hyphen/cli/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var UnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Remove the passphrase from the local .hxkey",
+	Long: `
+The unlock command decrypts the project's .hxkey and stores the key, and the
+project's earlier keys in the local keyring, in plain text again, readable only
+by its owner.
+
+Examples:
+  hyphen env key unlock
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunUnlock(cmd)
+	},
+}
+
+func RunUnlock(cmd *cobra.Command) error {
+	response := prompt.PromptYesNo(cmd, fmt.Sprintf("Store the secret key in %s without a passphrase?", secret.ManifestSecretFile), false)
+	if !response.Confirmed {
+		printer.Info("Unlock cancelled")
+		return nil
+	}
+
+	s, err := secret.UnlockLocalSecret()
+	if err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Unlocked secret key %d in %s", s.SecretKeyId, secret.ManifestSecretFile))
+	if projectId, err := flags.GetProjectID(); err == nil {
+		if _, err := secret.UnsealKeyring(projectId); err != nil {
+			printer.Warning(fmt.Sprintf("Earlier secret keys in the keyring are still sealed: %s", err))
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package pull
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/Hyphen/cli/internal/config"
@@ -16,15 +17,17 @@ import (
 	"github.com/Hyphen/cli/pkg/errors"
 	"github.com/Hyphen/cli/pkg/flags"
 	"github.com/Hyphen/cli/pkg/gitutil"
+	"github.com/Hyphen/cli/pkg/prompt"
 	"github.com/spf13/cobra"
 )
 
 var (
-	Silent     bool = false
-	forceFlag  bool
-	version    int
-	versionPtr *int = nil
-	printer    *cprint.CPrinter
+	Silent          bool = false
+	forceFlag       bool
+	interactiveFlag bool
+	version         int
+	versionPtr      *int = nil
+	printer         *cprint.CPrinter
 )
 
 const maxConcurrentEnvOps = 4
@@ -39,11 +42,18 @@ This command allows you to:
 - Pull a specific environment by name
 - Pull all environments for the application
 
-The pulled environments will be decrypted and saved as .env.[environment_name] files in your current directory.
+The pulled environments will be decrypted and saved as .env.[environment_name] files in the app directory.
+
+If you changed a local environment file since your last pull and the remote
+changed too, the remote changes are merged into it key by key. Keys changed on
+both sides are written between conflict markers for you to resolve, or with
+--interactive you choose which side to keep for each one. Use --force to
+overwrite your local changes instead.
 
 Examples:
   hyphen pull production
   hyphen pull
+  hyphen pull production --interactive
 
 After pulling, all environment variables will be locally available and ready for use.
 `,
@@ -199,6 +209,7 @@ func pullForApp(args []string, forceFlag bool, recorder *timing.Recorder) error
 
 func init() {
 	PullCmd.Flags().BoolVar(&forceFlag, "force", false, "Force overwrite of locally modified environment files")
+	PullCmd.Flags().BoolVar(&interactiveFlag, "interactive", false, "Choose how to resolve each merge conflict instead of writing conflict markers")
 	PullCmd.Flags().IntVar(&version, "version", 0, "Specify a version to pull")
 }
 
@@ -234,6 +245,7 @@ type pullEnvResult struct {
 	fileName  string
 	update    database.SecretUpdate
 	hasUpdate bool
+	merge     *env.MergeResult
 	err       error
 }
 
@@ -257,6 +269,7 @@ func (s *service) saveDecryptedEnvIntoFile(orgId, envName, appId string, secret
 	if result.fileName != "" {
 		_ = gitutil.EnsureGitignore(result.fileName)
 	}
+	printMergeResult(result)
 
 	return nil
 }
@@ -276,6 +289,9 @@ func (s *service) pullEnv(orgId, envName, appId string, secret models.Secret, cf
 	_, err = os.Stat(envFileName)
 	fileExists := !os.IsNotExist(err)
 
+	// mergeBase is set when local changes must be merged with the remote.
+	var mergeBase *database.Secret
+	var localContent string
 	if fileExists && !force {
 		currentLocal, err := env.New(envFileName)
 		if err != nil {
@@ -291,10 +307,14 @@ func (s *service) pullEnv(orgId, envName, appId string, secret models.Secret, cf
 		if dbSecretExists {
 			actual := currentLocal.HashData()
 			expectedHash := currentLocalSecret.Hash
-			if actual != expectedHash && !force {
+			if actual != expectedHash && versionPtr != nil {
 				result.err = fmt.Errorf("local \"%s\" environment has been modified. Use --force to overwrite", envName)
 				return result
 			}
+			if actual != expectedHash {
+				mergeBase = &currentLocalSecret
+				localContent = currentLocal.Data
+			}
 		}
 	}
 
@@ -315,7 +335,18 @@ func (s *service) pullEnv(orgId, envName, appId string, secret models.Secret, cf
 		return result
 	}
 
-	if err := os.WriteFile(envFileName, []byte(envDataDecrypted), 0600); err != nil {
+	content := envDataDecrypted
+	if mergeBase != nil {
+		merged, err := s.mergeWithLocal(orgId, appId, envName, secret, *mergeBase, localContent, envDataDecrypted, *e.Version)
+		if err != nil {
+			result.err = fmt.Errorf("failed to merge remote \"%s\" environment into your local changes: %w. Use --force to overwrite them", envName, err)
+			return result
+		}
+		content = merged.Content
+		result.merge = &merged
+	}
+
+	if err := os.WriteFile(envFileName, []byte(content), 0600); err != nil {
 		result.err = fmt.Errorf("failed to save decrypted environment %s to file %s: %w", envName, envFileName, err)
 		return result
 	}
@@ -334,6 +365,86 @@ func (s *service) pullEnv(orgId, envName, appId string, secret models.Secret, cf
 	return result
 }
 
+// mergeWithLocal three-way merges the remote version of an environment into
+// local changes made since the base version was pulled.
+func (s *service) mergeWithLocal(orgId, appId, envName string, secret models.Secret, base database.Secret, local, remote string, remoteVersion int) (env.MergeResult, error) {
+	baseContent := remote
+	if base.Version != remoteVersion {
+		baseEnv, err := s.envService.GetEnvironmentEnv(orgId, appId, envName, &secret.SecretKeyId, &base.Version)
+		if err != nil {
+			return env.MergeResult{}, fmt.Errorf("fetching base version %d: %w", base.Version, err)
+		}
+		baseContent, err = baseEnv.DecryptData(secret)
+		if err != nil {
+			return env.MergeResult{}, err
+		}
+	}
+
+	var resolve env.ConflictResolver
+	if interactiveFlag && !Silent {
+		resolve = conflictPrompter(envName, remoteVersion)
+	}
+	return env.Merge(baseContent, local, remote, fmt.Sprintf("remote v%d", remoteVersion), resolve)
+}
+
+// promptMu serializes conflict prompts from environments pulled concurrently.
+var promptMu sync.Mutex
+
+func conflictPrompter(envName string, remoteVersion int) env.ConflictResolver {
+	return func(c env.MergeConflict) (env.Resolution, error) {
+		promptMu.Lock()
+		defer promptMu.Unlock()
+
+		printer.Print(fmt.Sprintf("Conflict in %s: %s was %s locally and %s in remote v%d", envName, c.Key, describeChange(c.Base, c.Local), describeChange(c.Base, c.Remote), remoteVersion))
+		choice, err := prompt.PromptSelection([]prompt.Choice{
+			{Id: "local", Display: "Keep the local value"},
+			{Id: "remote", Display: "Use the remote value"},
+			{Id: "markers", Display: "Write conflict markers and resolve later"},
+		}, fmt.Sprintf("Resolve %s:", c.Key))
+		if err != nil {
+			return env.ResolveWithMarkers, err
+		}
+
+		switch choice.Id {
+		case "local":
+			return env.ResolveLocal, nil
+		case "remote":
+			return env.ResolveRemote, nil
+		default:
+			return env.ResolveWithMarkers, nil
+		}
+	}
+}
+
+func describeChange(base, value *string) string {
+	switch {
+	case value == nil:
+		return "deleted"
+	case base == nil:
+		return "added"
+	default:
+		return "changed"
+	}
+}
+
+func printMergeResult(result pullEnvResult) {
+	if Silent || result.merge == nil {
+		return
+	}
+
+	version := result.update.Version
+	if len(result.merge.Conflicts) == 0 {
+		printer.Info(fmt.Sprintf("Merged remote v%d into your local changes to %s (%d remote change(s) applied)", version, result.fileName, len(result.merge.Applied)))
+		return
+	}
+
+	keys := make([]string, len(result.merge.Conflicts))
+	for i, c := range result.merge.Conflicts {
+		keys[i] = c.Key
+	}
+	printer.Warning(fmt.Sprintf("Merged remote v%d into your local changes to %s with conflicts in %s. Resolve the conflict markers before pushing.", version, result.fileName, strings.Join(keys, ", ")))
+}
+
 func (s *service) getEnvPayloadForPull(orgId, appId, envName string, secret models.Secret, listedEnv *models.Env) (models.Env, error) {
 	if versionPtr == nil && listedEnv != nil && listedEnv.Data != "" && listedEnv.Version != nil &&
 		listedEnv.SecretKeyID != nil && *listedEnv.SecretKeyID == secret.SecretKeyId {
@@ -411,6 +522,7 @@ func (s *service) getAllEnvsAndDecryptIntoFiles(orgId, appId, projectId string,
 			continue
 		}
 		pulledEnvs = append(pulledEnvs, result.envName)
+		printMergeResult(result)
 		if result.hasUpdate {
 			updates = append(updates, result.update)
 		}
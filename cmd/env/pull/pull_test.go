@@ -1,6 +1,7 @@
 package pull
 
 import (
+	"encoding/base64"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/Hyphen/cli/internal/database"
 	"github.com/Hyphen/cli/internal/env"
 	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/internal/testutil"
 	"github.com/Hyphen/cli/internal/timing"
 	"github.com/Hyphen/cli/pkg/cprint"
 	"github.com/Hyphen/cli/pkg/flags"
@@ -392,3 +394,64 @@ func TestGetAllEnvsAndDecryptIntoFiles(t *testing.T) {
 		assert.NotContains(t, filteredEnvs, "deleted-env")
 	})
 }
+
+func TestPullEnvMergesLocalChanges(t *testing.T) {
+	theProjectId := "project-123"
+	theAppId := "app-456"
+	cfg := config.Config{ProjectId: &theProjectId, AppId: &theAppId}
+	secret := models.NewSecret(base64.StdEncoding.EncodeToString([]byte("test-secret-test-secret-test-secret-test-secret")))
+	secret.SecretKeyId = 111
+	baseVersion := 3
+
+	setup := func(t *testing.T, local, base, remote string) (*service, *env.MockEnvService) {
+		tempDir := t.TempDir()
+		originalDir, _ := os.Getwd()
+		os.Chdir(tempDir)
+		t.Cleanup(func() { os.Chdir(originalDir) })
+		printer = cprint.NewCPrinter(false)
+		assert.NoError(t, os.WriteFile(".env.production", []byte(local), 0600))
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{Version: baseVersion, Hash: models.HashData(base)}, true)
+		mockEnvService.On("GetEnvironmentEnv", "org-1", theAppId, "production", &secret.SecretKeyId, (*int)(nil)).Return(testutil.EncryptedEnv(t, secret, remote, 4), nil)
+		mockEnvService.On("GetEnvironmentEnv", "org-1", theAppId, "production", &secret.SecretKeyId, &baseVersion).Return(testutil.EncryptedEnv(t, secret, base, baseVersion), nil)
+		return newService(mockEnvService, mockDB, nil), mockEnvService
+	}
+
+	t.Run("merges_non_conflicting_changes", func(t *testing.T) {
+		svc, _ := setup(t, "A=1\nB=local\n", "A=1\nB=2\n", "A=remote\nB=2\n")
+
+		result := svc.pullEnv("org-1", "production", theAppId, secret, cfg, false, nil)
+
+		assert.NoError(t, result.err)
+		content, _ := os.ReadFile(".env.production")
+		assert.Equal(t, "A=remote\nB=local\n", string(content))
+		assert.Equal(t, []string{"A"}, result.merge.Applied)
+		assert.Equal(t, 4, result.update.Version)
+		assert.Equal(t, "A=remote\nB=2\n", result.update.Data)
+	})
+
+	t.Run("writes_conflict_markers", func(t *testing.T) {
+		svc, _ := setup(t, "A=local\n", "A=1\n", "A=remote\n")
+
+		result := svc.pullEnv("org-1", "production", theAppId, secret, cfg, false, nil)
+
+		assert.NoError(t, result.err)
+		content, _ := os.ReadFile(".env.production")
+		assert.Equal(t, "<<<<<<< local\nA=local\n=======\nA=remote\n>>>>>>> remote v4\n", string(content))
+		assert.Len(t, result.merge.Conflicts, 1)
+	})
+
+	t.Run("force_overwrites_local_changes", func(t *testing.T) {
+		svc, mockEnvService := setup(t, "A=local\n", "A=1\n", "A=remote\n")
+
+		result := svc.pullEnv("org-1", "production", theAppId, secret, cfg, true, nil)
+
+		assert.NoError(t, result.err)
+		content, _ := os.ReadFile(".env.production")
+		assert.Equal(t, "A=remote\n", string(content))
+		assert.Nil(t, result.merge)
+		mockEnvService.AssertNotCalled(t, "GetEnvironmentEnv", "org-1", theAppId, "production", &secret.SecretKeyId, &baseVersion)
+	})
+}
@@ -20,6 +20,8 @@ import (
 )
 
 var Silent bool = false
+var SkipValidation bool = false
+var forceFlag bool
 var printer *cprint.CPrinter
 
 const maxConcurrentEnvOps = 4
@@ -35,7 +37,14 @@ This command allows you to:
 -  Push a specific environment by name
 -  Encrypt and securely store your environment variables in Hyphen
 
-The command looks for .env files in the current directory with the naming convention .env.[environment_name].
+The command looks for .env files in the app directory with the naming convention .env.[environment_name].
+
+Push refuses to overwrite a remote environment that has moved on since your
+last pull, so a teammate's changes aren't silently lost. Pull and reconcile
+first, or use --force to overwrite the remote version anyway.
+
+If a .env.schema file exists, each environment is checked against it first and
+is not pushed when it violates the schema. Use --skip-validation to push anyway.
 
 Examples:
   hyphen push production
@@ -53,6 +62,11 @@ After pushing, all environment variables will be securely stored in Hyphen and a
 	},
 }
 
+func init() {
+	PushCmd.Flags().BoolVar(&SkipValidation, "skip-validation", false, "Push environments even if they violate .env.schema")
+	PushCmd.Flags().BoolVar(&forceFlag, "force", false, "Overwrite remote environments that changed since your last pull")
+}
+
 func RunPush(args []string, cmd *cobra.Command) error {
 	recorder := timing.NewRecorder()
 	defer recorder.Print(printer, "env push")
@@ -78,13 +92,6 @@ func RunPush(args []string, cmd *cobra.Command) error {
 	return runPushUsingSecret(args, secretValue, cmd, recorder)
 }
 
-func RunPushUsingSecret(args []string, secret models.Secret, cmd *cobra.Command) error {
-	recorder := timing.NewRecorder()
-	defer recorder.Print(printer, "env push")
-
-	return runPushUsingSecret(args, secret, cmd, recorder)
-}
-
 func runPushUsingSecret(args []string, secret models.Secret, cmd *cobra.Command, recorder *timing.Recorder) error {
 	var cfg config.Config
 	if err := recorder.Measure("config load", func() error {
@@ -157,6 +164,13 @@ func pushForMember(args []string, secret models.Secret, cmd *cobra.Command, reco
 	}
 
 	service := newService(env.NewService(), db, vinz.NewService())
+	if !SkipValidation {
+		schema, err := env.LoadSchema(env.SchemaFileName)
+		if err != nil {
+			return err
+		}
+		service.schema = schema
+	}
 
 	orgId, err := flags.GetOrganizationID()
 	if err != nil {
@@ -232,6 +246,8 @@ type service struct {
 	envService  env.EnvServicer
 	vinzService vinz.VinzServicer
 	db          database.Database
+	// schema, when set, is checked before each environment is pushed.
+	schema *env.Schema
 }
 
 func newService(envService env.EnvServicer, db database.Database, vinzService vinz.VinzServicer) *service {
@@ -239,6 +255,7 @@ func newService(envService env.EnvServicer, db database.Database, vinzService vi
 		envService,
 		vinzService,
 		db,
+		nil,
 	}
 }
 
@@ -299,6 +316,25 @@ func (s *service) pushEnv(orgID, envName, appID string, currentSecret models.Sec
 	}
 	plainData := localEnv.Data
 
+	// Conflict markers left by a pull are never pushed, even with
+	// --skip-validation: they'd overwrite every teammate's copy.
+	if line, ok := env.ConflictMarkerLine(plainData); ok {
+		result.err = fmt.Errorf("local %s has an unresolved merge conflict at line %d; edit the file to keep one value and remove the markers", envFileName, line)
+		return result
+	}
+
+	if s.schema != nil && !SkipValidation {
+		vars, err := env.Variables(plainData)
+		if err != nil {
+			result.err = fmt.Errorf("local %s is not a valid .env file: %w", envFileName, err)
+			return result
+		}
+		if violations := s.schema.Validate(envName, vars); len(violations) > 0 {
+			result.err = schemaViolationError(envFileName, violations)
+			return result
+		}
+	}
+
 	// Check local environment
 	currentLocalEnv, exists := s.db.GetSecret(database.SecretKey{
 		ProjectId: *cfg.ProjectId,
@@ -322,6 +358,13 @@ func (s *service) pushEnv(orgID, envName, appID string, currentSecret models.Sec
 		return result
 	}
 
+	if !forceFlag {
+		if err := checkForConflict(envName, currentLocalEnv, exists, latestCloudEnv, cloudExists); err != nil {
+			result.err = err
+			return result
+		}
+	}
+
 	// try pushing version+1
 	newVersion := nextEnvVersion(currentLocalEnv, exists, latestCloudEnv, cloudExists)
 	localEnv.Version = &newVersion
@@ -363,6 +406,10 @@ func (s *service) pushEnv(orgID, envName, appID string, currentSecret models.Sec
 	return result
 }
 
+func schemaViolationError(fileName string, violations []env.SchemaViolation) error {
+	return fmt.Errorf("local %s violates %s and was not pushed (use --skip-validation to push anyway):\n%s", fileName, env.SchemaFileName, env.FormatViolations(violations))
+}
+
 func (s *service) resolveCloudEnvForPush(orgID, appID, envName string, cloudEnv *models.Env) (models.Env, bool, error) {
 	if cloudEnv == nil {
 		return models.Env{}, false, nil
@@ -382,6 +429,24 @@ func (s *service) resolveCloudEnvForPush(orgID, appID, envName string, cloudEnv
 	return latestEnv, true, nil
 }
 
+// checkForConflict returns an error when the remote environment has versions
+// the local copy isn't based on, which pushing would silently discard.
+func checkForConflict(envName string, local database.Secret, localExists bool, cloud models.Env, cloudExists bool) error {
+	if !cloudExists || cloud.Version == nil {
+		return nil
+	}
+
+	if !localExists {
+		return errors.Wrapf(errors.ErrConflict, "remote %s environment already has version %d, which you haven't pulled. Pull it first, or use --force to overwrite it", envName, *cloud.Version)
+	}
+
+	if *cloud.Version > local.Version {
+		return errors.Wrapf(errors.ErrConflict, "remote %s environment is at version %d but your local copy is based on version %d. Pull and reconcile the changes first, or use --force to overwrite them", envName, *cloud.Version, local.Version)
+	}
+
+	return nil
+}
+
 func nextEnvVersion(local database.Secret, localExists bool, cloud models.Env, cloudExists bool) int {
 	version := 0
 	if localExists && local.Version > version {
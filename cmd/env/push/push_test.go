@@ -78,8 +78,8 @@ func TestPushEnv(t *testing.T) {
 		}
 		secret := getTestSecret(theProjectSecretKeyId)
 
-		// Local env exists
-		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{Version: 3, Hash: "different-hash"}, true)
+		// Local env exists and is based on the latest cloud version
+		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{Version: 5, Hash: "different-hash"}, true)
 
 		// Cloud env exists with different secret key
 		cloudEnv := models.Env{
@@ -167,6 +167,118 @@ func TestPushEnv(t *testing.T) {
 		assert.False(t, result.hasUpdate)
 		mockEnvService.AssertNotCalled(t, "PutEnvironmentEnv", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	})
+
+	t.Run("refuses_env_that_violates_schema", func(t *testing.T) {
+		withEnvFile(t, "production", "PORT=eighty")
+
+		mockEnvService := env.NewMockEnvService()
+		svc := newService(mockEnvService, new(database.MockDatabase), nil)
+		schema, err := env.ParseSchema([]byte("variables:\n  DATABASE_URL:\n    required: true\n  PORT:\n    type: int\n"))
+		assert.NoError(t, err)
+		svc.schema = schema
+
+		theProjectId := "project-123"
+		theAppId := "app-456"
+		cfg := config.Config{ProjectId: &theProjectId, AppId: &theAppId}
+
+		result := svc.pushEnv("org-1", "production", theAppId, getTestSecret(1), cfg, nil)
+
+		assert.ErrorContains(t, result.err, "local .env.production violates .env.schema")
+		assert.ErrorContains(t, result.err, "DATABASE_URL: is required but not set")
+		assert.ErrorContains(t, result.err, "PORT: is not an integer")
+		assert.NotContains(t, result.err.Error(), "eighty")
+		assert.False(t, result.hasUpdate)
+		mockEnvService.AssertNotCalled(t, "PutEnvironmentEnv", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("pushes_unparseable_env_without_schema", func(t *testing.T) {
+		withEnvFile(t, "staging", "not a dotenv line")
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		svc := newService(mockEnvService, mockDB, nil)
+		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{}, false)
+		mockEnvService.On("PutEnvironmentEnv", "org-1", "app-456", "staging", int64(1), mock.Anything).Return(nil).Once()
+
+		theProjectId := "project-123"
+		theAppId := "app-456"
+		cfg := config.Config{ProjectId: &theProjectId, AppId: &theAppId}
+
+		result := svc.pushEnv("org-1", "staging", theAppId, getTestSecret(1), cfg, nil)
+
+		assert.NoError(t, result.err)
+		assert.True(t, result.hasUpdate)
+	})
+
+	t.Run("refuses_env_with_conflict_markers", func(t *testing.T) {
+		withEnvFile(t, "staging", "A=1\n<<<<<<< local\nB=2\n=======\nB=3\n>>>>>>> remote staging\n")
+
+		mockEnvService := env.NewMockEnvService()
+		svc := newService(mockEnvService, new(database.MockDatabase), nil)
+
+		theProjectId := "project-123"
+		theAppId := "app-456"
+		cfg := config.Config{ProjectId: &theProjectId, AppId: &theAppId}
+
+		result := svc.pushEnv("org-1", "staging", theAppId, getTestSecret(1), cfg, nil)
+
+		assert.ErrorContains(t, result.err, "local .env.staging has an unresolved merge conflict at line 2")
+		mockEnvService.AssertNotCalled(t, "PutEnvironmentEnv", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestPushEnvConflicts(t *testing.T) {
+	theProjectId := "project-123"
+	theAppId := "app-456"
+	cfg := config.Config{ProjectId: &theProjectId, AppId: &theAppId}
+	var theSecretKeyId int64 = 11111
+	theCloudVersion := 5
+	cloudEnv := models.Env{SecretKeyID: &theSecretKeyId, Version: &theCloudVersion}
+
+	t.Run("refuses_when_remote_moved_on", func(t *testing.T) {
+		withEnvFile(t, "production", "KEY=mine")
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		svc := newService(mockEnvService, mockDB, nil)
+		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{Version: 3, Hash: "old-hash"}, true)
+
+		result := svc.pushEnv("org-1", "production", theAppId, getTestSecret(theSecretKeyId), cfg, &cloudEnv)
+
+		assert.True(t, errors.Is(result.err, errors.ErrConflict))
+		assert.ErrorContains(t, result.err, "remote production environment is at version 5 but your local copy is based on version 3")
+		mockEnvService.AssertNotCalled(t, "PutEnvironmentEnv", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses_when_remote_was_never_pulled", func(t *testing.T) {
+		withEnvFile(t, "production", "KEY=mine")
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		svc := newService(mockEnvService, mockDB, nil)
+		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{}, false)
+
+		result := svc.pushEnv("org-1", "production", theAppId, getTestSecret(theSecretKeyId), cfg, &cloudEnv)
+
+		assert.True(t, errors.Is(result.err, errors.ErrConflict))
+		assert.ErrorContains(t, result.err, "already has version 5, which you haven't pulled")
+	})
+
+	t.Run("force_overwrites", func(t *testing.T) {
+		withEnvFile(t, "production", "KEY=mine")
+		forceFlag = true
+		t.Cleanup(func() { forceFlag = false })
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		svc := newService(mockEnvService, mockDB, nil)
+		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{Version: 3, Hash: "old-hash"}, true)
+		mockEnvService.On("PutEnvironmentEnv", "org-1", theAppId, "production", theSecretKeyId, mock.Anything).Return(nil).Once()
+
+		result := svc.pushEnv("org-1", "production", theAppId, getTestSecret(theSecretKeyId), cfg, &cloudEnv)
+
+		assert.NoError(t, result.err)
+		assert.Equal(t, 6, result.update.Version)
+	})
 }
 
 func TestNextEnvVersionUsesHighestKnownVersion(t *testing.T) {
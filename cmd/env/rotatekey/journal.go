@@ -0,0 +1,158 @@
+package rotatekey
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/internal/secret"
+	"github.com/Hyphen/cli/pkg/errors"
+)
+
+// journalFile records an unfinished rotation in the directory it was started
+// from, so it can be resumed or rolled back after a crash or failed request.
+const journalFile = ".hxkey.rotation"
+
+// journal is the persisted state of a key rotation. It is rewritten after
+// every step, so it always says which environments already use the new key.
+type journal struct {
+	OrganizationId string                `json:"organization_id"`
+	ProjectId      string                `json:"project_id"`
+	Location       secret.SecretLocation `json:"location"`
+	OldSecretKeyId int64                 `json:"old_secret_key_id"`
+	NewSecretKeyId int64                 `json:"new_secret_key_id"`
+	// OldKey is the old secret encrypted with the new one and NewKey the new
+	// secret encrypted with the old one, so whichever key is in the key store
+	// can recover the other without either being written in plain text.
+	OldKey string `json:"old_key"`
+	NewKey string `json:"new_key"`
+	// Switched is set once the new key has replaced the old one in the store.
+	Switched bool         `json:"switched"`
+	Envs     []journalEnv `json:"envs"`
+}
+
+type journalEnv struct {
+	AppId   string `json:"app_id"`
+	EnvName string `json:"env_name"`
+	// BaseVersion is the version re-encrypted and Hash the hash of its
+	// decrypted content, which verification compares against.
+	BaseVersion int    `json:"base_version"`
+	Hash        string `json:"hash"`
+	// PushedVersion is the version pushed with the new key, or 0 if the
+	// environment hasn't been re-encrypted yet.
+	PushedVersion int  `json:"pushed_version,omitempty"`
+	Verified      bool `json:"verified,omitempty"`
+}
+
+func newJournal(organizationId, projectId string, location secret.SecretLocation, oldSecret, newSecret models.Secret) (*journal, error) {
+	oldKey, err := sealSecret(newSecret, oldSecret)
+	if err != nil {
+		return nil, err
+	}
+	newKey, err := sealSecret(oldSecret, newSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &journal{
+		OrganizationId: organizationId,
+		ProjectId:      projectId,
+		Location:       location,
+		OldSecretKeyId: oldSecret.SecretKeyId,
+		NewSecretKeyId: newSecret.SecretKeyId,
+		OldKey:         oldKey,
+		NewKey:         newKey,
+	}, nil
+}
+
+// secrets recovers both keys of the rotation from the key currently in the
+// store.
+func (j *journal) secrets(current models.Secret) (oldSecret, newSecret models.Secret, err error) {
+	switch current.SecretKeyId {
+	case j.OldSecretKeyId:
+		newSecret, err = openSecret(current, j.NewKey)
+		return current, newSecret, err
+	case j.NewSecretKeyId:
+		oldSecret, err = openSecret(current, j.OldKey)
+		return oldSecret, current, err
+	default:
+		return models.Secret{}, models.Secret{}, fmt.Errorf("the current secret key %d is neither the old key %d nor the new key %d of the interrupted rotation", current.SecretKeyId, j.OldSecretKeyId, j.NewSecretKeyId)
+	}
+}
+
+func sealSecret(with, s models.Secret) (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", errors.Wrap(err, "Error encoding JSON")
+	}
+	return with.Encrypt(string(data))
+}
+
+func openSecret(with models.Secret, sealed string) (models.Secret, error) {
+	data, err := with.Decrypt(sealed)
+	if err != nil {
+		return models.Secret{}, errors.Wrap(err, "Failed to decrypt the key backup in the rotation journal")
+	}
+	var s models.Secret
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return models.Secret{}, errors.Wrap(err, "Failed to decode the key backup in the rotation journal")
+	}
+	return s, nil
+}
+
+// loadJournal returns the journal of an unfinished rotation, or nil if there
+// is none.
+func loadJournal() (*journal, error) {
+	data, err := os.ReadFile(journalFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read %s", journalFile)
+	}
+
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode %s", journalFile)
+	}
+	return &j, nil
+}
+
+// save writes the journal atomically so a crash never leaves it half written.
+func (j *journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Error encoding JSON")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(journalFile), journalFile+".*")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write %s", journalFile)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "Failed to write %s", journalFile)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "Failed to write %s", journalFile)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to write %s", journalFile)
+	}
+	if err := os.Rename(tmp.Name(), journalFile); err != nil {
+		return errors.Wrapf(err, "Failed to write %s", journalFile)
+	}
+	return nil
+}
+
+func (j *journal) remove() error {
+	if err := os.Remove(journalFile); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "Failed to remove %s", journalFile)
+	}
+	return nil
+}
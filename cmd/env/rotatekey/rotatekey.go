@@ -3,26 +3,50 @@ package rotatekey
 import (
 	"fmt"
 
-	"github.com/Hyphen/cli/cmd/env/pull"
-	"github.com/Hyphen/cli/cmd/env/push"
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
 	"github.com/Hyphen/cli/internal/secret"
 	"github.com/Hyphen/cli/pkg/cprint"
 	"github.com/Hyphen/cli/pkg/errors"
 	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/Hyphen/cli/pkg/gitutil"
 	"github.com/Hyphen/cli/pkg/prompt"
 	"github.com/spf13/cobra"
 )
 
 var (
-	forceFlag bool
-	printer   *cprint.CPrinter
+	forceFlag    bool
+	resumeFlag   bool
+	rollbackFlag bool
+	printer      *cprint.CPrinter
 )
 
 var RotateCmd = &cobra.Command{
 	Use:   "rotate-key",
 	Short: "Rotate the encryption key and update all environments",
-	Long:  `This command rotates the encryption key and updates all environments with the new key.`,
-	Args:  cobra.NoArgs,
+	Long: `
+This command rotates the encryption key and updates all environments with the new key.
+
+Every environment is re-encrypted straight from its latest remote version and
+pushed as a new version. The new key only replaces the old one once every
+environment has been verified to decrypt with it. Local .env files are not
+touched.
+
+The old key is kept in the local keyring (.hxkeyring), so versions pushed
+before the rotation can still be pulled and rolled back to.
+
+Progress is recorded in a .hxkey.rotation journal, which also holds a backup of
+each key encrypted with the other one. If a rotation is interrupted, run it
+again with --resume to finish it or with --rollback to return every
+environment to the old key.
+
+Examples:
+  hyphen env rotate-key
+  hyphen env rotate-key --resume
+  hyphen env rotate-key --rollback
+`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		printer = cprint.NewCPrinter(flags.VerboseFlag)
 		if err := runRotateKey(cmd); err != nil {
@@ -34,7 +58,11 @@ var RotateCmd = &cobra.Command{
 
 func init() {
 	RotateCmd.Flags().BoolVar(&forceFlag, "force", false, "Force overwrite of locally modified environment files")
+	RotateCmd.Flags().MarkDeprecated("force", "rotation no longer writes local .env files")
 	RotateCmd.Flags().BoolVarP(&flags.LocalSecret, "localSecret", "l", false, "Use local secret key file instead of Hyphen's secure key store")
+	RotateCmd.Flags().BoolVar(&resumeFlag, "resume", false, "Finish an interrupted key rotation")
+	RotateCmd.Flags().BoolVar(&rollbackFlag, "rollback", false, "Undo an interrupted key rotation")
+	RotateCmd.MarkFlagsMutuallyExclusive("resume", "rollback")
 }
 
 func runRotateKey(cmd *cobra.Command) error {
@@ -49,8 +77,22 @@ func runRotateKey(cmd *cobra.Command) error {
 		return errors.Wrap(err, "Failed to get project ID")
 	}
 
+	j, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	if resumeFlag || rollbackFlag {
+		if j == nil {
+			return fmt.Errorf("there is no interrupted key rotation in this directory")
+		}
+		return recoverRotation(cmd, j)
+	}
+	if j != nil {
+		return fmt.Errorf("a key rotation was interrupted. Run `hx env rotate-key --resume` to finish it or `hx env rotate-key --rollback` to undo it")
+	}
+
 	// Get the current location
-	_, location, err := secret.LoadSecret(organizationId, projectId)
+	oldSecret, location, err := secret.LoadSecret(organizationId, projectId)
 	if err != nil {
 		return errors.Wrap(err, "unable to load secret for rotation")
 	}
@@ -65,11 +107,10 @@ func runRotateKey(cmd *cobra.Command) error {
 	}
 
 	// Display warning and prompt for confirmation
-	printer.Warning("You are about to rotate the encryption key. This action is irreversible and will affect all environments.")
-	printer.Info("Make sure you have a backup of your current configuration before proceeding.")
+	printer.Warning("You are about to rotate the encryption key. This action will affect all environments.")
 	printer.Info("This action will:")
 	printer.Info("  1. Generate a new encryption key")
-	printer.Info("  2. Re-encrypt all environment variables with the new key")
+	printer.Info("  2. Re-encrypt all environment variables with the new key and verify them")
 	printer.Info(fmt.Sprintf("  3. Update the secret key stored %s", locationDesc))
 	printer.Info("\nAre you absolutely sure you want to proceed?")
 
@@ -82,19 +123,37 @@ func runRotateKey(cmd *cobra.Command) error {
 	// Proceed with key rotation
 	printer.Info("Proceeding with key rotation...")
 
-	//Get all the envs
-	pull.Silent = true
-	if err := pull.RunPull([]string{}, forceFlag); err != nil {
+	newSecret, err := models.GenerateSecret()
+	if err != nil {
 		return err
 	}
 
-	newSecret, err := secret.RotateSecret()
+	j, err = newJournal(organizationId, projectId, location, oldSecret, newSecret)
 	if err != nil {
 		return err
 	}
 
-	push.Silent = true
-	push.RunPushUsingSecret([]string{}, newSecret, cmd)
+	svc, err := newRotationService(j)
+	if err != nil {
+		return err
+	}
+
+	appIds, err := flags.GetProjectApplicationIDs()
+	if err != nil {
+		return err
+	}
+	if err := svc.plan(j, appIds, oldSecret); err != nil {
+		return errors.Wrap(err, "Key rotation was not started")
+	}
+	if err := j.save(); err != nil {
+		return err
+	}
+	_ = gitutil.EnsureGitignore(journalFile)
+	keepInKeyring(projectId, oldSecret)
+
+	if err := finishRotation(svc, j, oldSecret, newSecret); err != nil {
+		return err
+	}
 
 	printer.Success("Key rotation completed successfully.")
 	printer.Info(fmt.Sprintf("New encryption key has been generated and stored %s", locationDesc))
@@ -103,3 +162,92 @@ func runRotateKey(cmd *cobra.Command) error {
 
 	return nil
 }
+
+// recoverRotation resumes or rolls back the rotation recorded in j.
+func recoverRotation(cmd *cobra.Command, j *journal) error {
+	current, _, err := secret.LoadSecret(j.OrganizationId, j.ProjectId)
+	if err != nil {
+		return errors.Wrap(err, "unable to load secret for rotation")
+	}
+	oldSecret, newSecret, err := j.secrets(current)
+	if err != nil {
+		return err
+	}
+
+	svc, err := newRotationService(j)
+	if err != nil {
+		return err
+	}
+
+	if resumeFlag {
+		keepInKeyring(j.ProjectId, oldSecret)
+		printer.Info(fmt.Sprintf("Resuming the rotation from secret key %d to %d...", j.OldSecretKeyId, j.NewSecretKeyId))
+		if err := finishRotation(svc, j, oldSecret, newSecret); err != nil {
+			return err
+		}
+		printer.Success("Key rotation completed successfully.")
+		printer.Info("Please ensure all team members pull the latest changes.")
+		return nil
+	}
+
+	printer.Warning(fmt.Sprintf("This returns every environment to secret key %d and discards new key %d.", j.OldSecretKeyId, j.NewSecretKeyId))
+	response := prompt.PromptYesNo(cmd, "Roll back the key rotation?", false)
+	if !response.Confirmed {
+		printer.Info("Rollback cancelled.")
+		return nil
+	}
+
+	// Versions pushed during the rotation stay encrypted with the new key.
+	keepInKeyring(j.ProjectId, newSecret)
+	if err := svc.rollback(j, oldSecret, newSecret); err != nil {
+		return errors.Wrap(err, "Key rotation rollback did not finish; run `hx env rotate-key --rollback` again")
+	}
+	if err := svc.syncDatabase(j, oldSecret); err != nil {
+		printer.Warning(fmt.Sprintf("Failed to update the local environment records: %s", err))
+	}
+	if err := j.remove(); err != nil {
+		return err
+	}
+
+	printer.Success("Key rotation rolled back. All environments use the old key again.")
+	return nil
+}
+
+// finishRotation carries every environment over to the new key, verifies
+// them and only then switches the stored key.
+func finishRotation(svc *service, j *journal, oldSecret, newSecret models.Secret) error {
+	err := svc.reencrypt(j, oldSecret, newSecret)
+	if err == nil {
+		err = svc.verify(j, newSecret)
+	}
+	if err == nil {
+		err = svc.switchKey(j, newSecret)
+	}
+	if err != nil {
+		return fmt.Errorf("key rotation did not finish: %w. Run `hx env rotate-key --resume` to finish it or `hx env rotate-key --rollback` to undo it", err)
+	}
+
+	if err := svc.syncDatabase(j, newSecret); err != nil {
+		printer.Warning(fmt.Sprintf("Failed to update the local environment records: %s. Run `hx env pull` to refresh them.", err))
+	}
+	return j.remove()
+}
+
+// keepInKeyring adds a key that is being replaced to the local keyring so
+// versions encrypted with it can still be pulled and rolled back to.
+func keepInKeyring(projectId string, s models.Secret) {
+	if err := secret.AddToKeyring(projectId, s); err != nil {
+		printer.Warning(fmt.Sprintf("Failed to add secret key %d to the keyring: %s", s.SecretKeyId, err))
+	}
+}
+
+func newRotationService(j *journal) (*service, error) {
+	db, err := database.Restore()
+	if err != nil {
+		return nil, err
+	}
+
+	return newService(env.NewService(), db, func(s models.Secret) error {
+		return secret.SaveSecret(j.OrganizationId, j.ProjectId, j.Location, s)
+	}), nil
+}
@@ -0,0 +1,291 @@
+package rotatekey
+
+import (
+	"fmt"
+
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/pkg/errors"
+)
+
+type service struct {
+	envService env.EnvServicer
+	db         database.Database
+	saveSecret func(models.Secret) error
+}
+
+func newService(envService env.EnvServicer, db database.Database, saveSecret func(models.Secret) error) *service {
+	return &service{
+		envService,
+		db,
+		saveSecret,
+	}
+}
+
+// plan records every environment of the given apps in the journal after
+// checking that its latest version decrypts with the old key, so a rotation
+// never starts with data it can't carry over.
+func (s *service) plan(j *journal, appIds []string, oldSecret models.Secret) error {
+	for _, appId := range appIds {
+		names, err := s.envNames(j.OrganizationId, j.ProjectId, appId)
+		if err != nil {
+			return err
+		}
+
+		for _, envName := range names {
+			e, content, err := s.latest(j.OrganizationId, appId, envName, oldSecret)
+			if err != nil {
+				return err
+			}
+			j.Envs = append(j.Envs, journalEnv{
+				AppId:       appId,
+				EnvName:     envName,
+				BaseVersion: *e.Version,
+				Hash:        models.HashData(content),
+			})
+		}
+	}
+	return nil
+}
+
+// envNames returns the environments of an app that have been pushed and
+// haven't been deleted.
+func (s *service) envNames(orgId, projectId, appId string) ([]string, error) {
+	pushed, err := env.ListAllEnvs(s.envService, orgId, appId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the environments of app %s: %w", appId, err)
+	}
+	current, err := env.ListAllEnvironments(s.envService, orgId, projectId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the environments of project %s: %w", projectId, err)
+	}
+
+	exists := map[string]bool{"default": true}
+	for _, e := range current {
+		exists[e.AlternateID] = true
+	}
+
+	var names []string
+	for _, e := range pushed {
+		name := "default"
+		if e.ProjectEnv != nil {
+			name = e.ProjectEnv.AlternateID
+		}
+		if exists[name] {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// latest fetches and decrypts the latest version of an environment.
+func (s *service) latest(orgId, appId, envName string, secret models.Secret) (models.Env, string, error) {
+	e, err := s.envService.GetEnvironmentEnv(orgId, appId, envName, nil, nil)
+	if err != nil {
+		return models.Env{}, "", fmt.Errorf("failed to fetch the %s environment: %w", envName, err)
+	}
+	if e.Version == nil {
+		return models.Env{}, "", fmt.Errorf("remote %s environment has no version", envName)
+	}
+
+	content, err := e.DecryptData(secret)
+	if err != nil {
+		return models.Env{}, "", fmt.Errorf("failed to decrypt the %s environment with secret key %d: %w", envName, secret.SecretKeyId, err)
+	}
+	return e, content, nil
+}
+
+// reencrypt pushes every environment not yet carried over as a new version
+// encrypted with the new key, saving the journal after each one.
+func (s *service) reencrypt(j *journal, oldSecret, newSecret models.Secret) error {
+	for i := range j.Envs {
+		je := &j.Envs[i]
+		if je.PushedVersion != 0 {
+			continue
+		}
+
+		// A previous run may have pushed this env but crashed before saving
+		// the journal; its latest version then already uses the new key.
+		e, err := s.envService.GetEnvironmentEnv(j.OrganizationId, je.AppId, je.EnvName, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to fetch the %s environment: %w", je.EnvName, err)
+		}
+		if e.Version == nil {
+			return fmt.Errorf("remote %s environment has no version", je.EnvName)
+		}
+		if encryptedWith(e) == newSecret.SecretKeyId {
+			je.PushedVersion = *e.Version
+			if err := j.save(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		content, err := e.DecryptData(oldSecret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt the %s environment with the old key: %w", je.EnvName, err)
+		}
+		// Someone may have pushed since the rotation was planned; carry over
+		// whatever is latest.
+		je.BaseVersion = *e.Version
+		je.Hash = models.HashData(content)
+
+		version, err := s.put(j.OrganizationId, je.AppId, je.EnvName, content, *e.Version+1, encryptedWith(e), newSecret)
+		if err != nil {
+			return err
+		}
+		je.PushedVersion = version
+		if err := j.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verify checks that the latest version of every environment decrypts with
+// the new key to the content that was re-encrypted.
+func (s *service) verify(j *journal, newSecret models.Secret) error {
+	for i := range j.Envs {
+		je := &j.Envs[i]
+		if je.Verified {
+			continue
+		}
+
+		e, err := s.envService.GetEnvironmentEnv(j.OrganizationId, je.AppId, je.EnvName, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to fetch the %s environment: %w", je.EnvName, err)
+		}
+		if encryptedWith(e) != newSecret.SecretKeyId {
+			// Pushed with the old key after being re-encrypted; the next
+			// resume carries it over again.
+			je.PushedVersion = 0
+			if err := j.save(); err != nil {
+				return err
+			}
+			return fmt.Errorf("the %s environment was pushed with the old key during the rotation", je.EnvName)
+		}
+		content, err := e.DecryptData(newSecret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt the %s environment with the new key: %w", je.EnvName, err)
+		}
+		if models.HashData(content) != je.Hash {
+			return fmt.Errorf("the %s environment changed during the rotation", je.EnvName)
+		}
+
+		je.Verified = true
+		if err := j.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// switchKey stores the new key in place of the old one.
+func (s *service) switchKey(j *journal, newSecret models.Secret) error {
+	if j.Switched {
+		return nil
+	}
+	if err := s.saveSecret(newSecret); err != nil {
+		return err
+	}
+	j.Switched = true
+	return j.save()
+}
+
+// rollback re-encrypts every environment already carried over back to the
+// old key and restores the old key in the store.
+func (s *service) rollback(j *journal, oldSecret, newSecret models.Secret) error {
+	for i := range j.Envs {
+		je := &j.Envs[i]
+
+		e, err := s.envService.GetEnvironmentEnv(j.OrganizationId, je.AppId, je.EnvName, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to fetch the %s environment: %w", je.EnvName, err)
+		}
+		if e.Version == nil || encryptedWith(e) != newSecret.SecretKeyId {
+			je.PushedVersion = 0
+			continue
+		}
+
+		content, err := e.DecryptData(newSecret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt the %s environment with the new key: %w", je.EnvName, err)
+		}
+		if _, err := s.put(j.OrganizationId, je.AppId, je.EnvName, content, *e.Version+1, newSecret.SecretKeyId, oldSecret); err != nil {
+			return err
+		}
+		je.PushedVersion = 0
+		je.Verified = false
+		if err := j.save(); err != nil {
+			return err
+		}
+	}
+
+	if j.Switched {
+		if err := s.saveSecret(oldSecret); err != nil {
+			return err
+		}
+		j.Switched = false
+		if err := j.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncDatabase moves the local record of each environment to the version the
+// rotation pushed, so later pushes don't mistake it for a remote change. Only
+// records of the same content are moved; local files are never touched.
+func (s *service) syncDatabase(j *journal, secret models.Secret) error {
+	var updates []database.SecretUpdate
+	for _, je := range j.Envs {
+		key := database.SecretKey{ProjectId: j.ProjectId, AppId: je.AppId, EnvName: je.EnvName}
+		known, ok := s.db.GetSecret(key)
+		if !ok {
+			continue
+		}
+
+		e, content, err := s.latest(j.OrganizationId, je.AppId, je.EnvName, secret)
+		if err != nil {
+			return err
+		}
+		if known.Hash != models.HashData(content) || known.Version >= *e.Version {
+			continue
+		}
+		updates = append(updates, database.SecretUpdate{Key: key, Data: content, Version: *e.Version})
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return s.db.UpsertSecrets(updates)
+}
+
+func (s *service) put(orgId, appId, envName, content string, version int, replacingSecretKeyId int64, secret models.Secret) (int, error) {
+	e := env.NewFromContent(content)
+	e.Version = &version
+	e.SecretKeyID = &secret.SecretKeyId
+
+	data, err := e.EncryptData(secret)
+	if err != nil {
+		return 0, err
+	}
+	e.Data = data
+
+	if err := s.envService.PutEnvironmentEnv(orgId, appId, envName, replacingSecretKeyId, e); err != nil {
+		return 0, errors.Wrapf(err, "Failed to push the %s environment", envName)
+	}
+	return version, nil
+}
+
+// encryptedWith returns the id of the secret key a remote env is encrypted
+// with.
+func encryptedWith(e models.Env) int64 {
+	if id, ok := models.EncryptedSecretKeyID(e.Data); ok {
+		return id
+	}
+	if e.SecretKeyID != nil {
+		return *e.SecretKeyID
+	}
+	return 0
+}
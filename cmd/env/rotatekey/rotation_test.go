@@ -0,0 +1,183 @@
+package rotatekey
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/internal/testutil"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeEnvService keeps the latest version of each environment in memory.
+type fakeEnvService struct {
+	*env.MockEnvService
+	envs    map[string]models.Env
+	failPut map[string]bool
+}
+
+func newFakeEnvService() *fakeEnvService {
+	return &fakeEnvService{
+		MockEnvService: env.NewMockEnvService(),
+		envs:           make(map[string]models.Env),
+		failPut:        make(map[string]bool),
+	}
+}
+
+func (f *fakeEnvService) GetEnvironmentEnv(organizationId, appId, environmentId string, secretKeyId *int64, version *int) (models.Env, error) {
+	return f.envs[environmentId], nil
+}
+
+func (f *fakeEnvService) PutEnvironmentEnv(organizationId, appId, environmentId string, secretKeyId int64, e models.Env) error {
+	if f.failPut[environmentId] {
+		return errors.New("request failed")
+	}
+	f.envs[environmentId] = e
+	return nil
+}
+
+func (f *fakeEnvService) ListEnvs(organizationId, appId string, size, page int) ([]models.Env, error) {
+	return []models.Env{
+		{ProjectEnv: nil},
+		{ProjectEnv: &models.ProjectEnvironmentReference{AlternateID: "production"}},
+		{ProjectEnv: &models.ProjectEnvironmentReference{AlternateID: "deleted"}},
+	}, nil
+}
+
+func (f *fakeEnvService) ListEnvironments(organizationId, projectId string, size, page int) ([]models.Environment, error) {
+	return []models.Environment{{AlternateID: "production"}}, nil
+}
+
+func (f *fakeEnvService) push(t *testing.T, envName, content string, version int, secret models.Secret) {
+	t.Helper()
+	e := env.NewFromContent(content)
+	e.Version = &version
+	e.SecretKeyID = &secret.SecretKeyId
+	data, err := e.EncryptData(secret)
+	assert.NoError(t, err)
+	e.Data = data
+	f.envs[envName] = e
+}
+
+func (f *fakeEnvService) content(t *testing.T, envName string, secret models.Secret) string {
+	t.Helper()
+	e := f.envs[envName]
+	content, err := e.DecryptData(secret)
+	assert.NoError(t, err)
+	return content
+}
+
+func testSecret(id int64, key string) models.Secret {
+	s := models.NewSecret(base64.StdEncoding.EncodeToString([]byte(key)))
+	s.SecretKeyId = id
+	return s
+}
+
+type rotationFixture struct {
+	envService *fakeEnvService
+	svc        *service
+	saved      []models.Secret
+	oldSecret  models.Secret
+	newSecret  models.Secret
+	journal    *journal
+}
+
+func setupRotation(t *testing.T) *rotationFixture {
+	t.Helper()
+	testutil.ChdirTemp(t)
+	printer = cprint.NewCPrinter(false)
+
+	f := &rotationFixture{
+		envService: newFakeEnvService(),
+		oldSecret:  testSecret(1, "old-secret-old-secret-old-secret-old-secret"),
+		newSecret:  testSecret(2, "new-secret-new-secret-new-secret-new-secret"),
+	}
+	f.envService.push(t, "default", "A=1\n", 3, f.oldSecret)
+	f.envService.push(t, "production", "B=2\n", 7, f.oldSecret)
+
+	mockDB := new(database.MockDatabase)
+	mockDB.On("GetSecret", mock.Anything).Return(database.Secret{}, false)
+	f.svc = newService(f.envService, mockDB, func(s models.Secret) error {
+		f.saved = append(f.saved, s)
+		return nil
+	})
+
+	var err error
+	f.journal, err = newJournal("org-1", "project-1", 0, f.oldSecret, f.newSecret)
+	assert.NoError(t, err)
+	assert.NoError(t, f.svc.plan(f.journal, []string{"app-1"}, f.oldSecret))
+	assert.NoError(t, f.journal.save())
+	return f
+}
+
+func TestRotation(t *testing.T) {
+	t.Run("reencrypts_verifies_and_switches_key", func(t *testing.T) {
+		f := setupRotation(t)
+
+		assert.Len(t, f.journal.Envs, 2)
+		assert.NoError(t, finishRotation(f.svc, f.journal, f.oldSecret, f.newSecret))
+
+		assert.Equal(t, "A=1\n", f.envService.content(t, "default", f.newSecret))
+		assert.Equal(t, "B=2\n", f.envService.content(t, "production", f.newSecret))
+		assert.Equal(t, 8, *f.envService.envs["production"].Version)
+		assert.Equal(t, []models.Secret{f.newSecret}, f.saved)
+		_, err := os.Stat(journalFile)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("keeps_old_key_until_every_env_is_carried_over", func(t *testing.T) {
+		f := setupRotation(t)
+		f.envService.failPut["production"] = true
+
+		err := finishRotation(f.svc, f.journal, f.oldSecret, f.newSecret)
+
+		assert.ErrorContains(t, err, "rotate-key --resume")
+		assert.Empty(t, f.saved)
+		saved, err := loadJournal()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, saved.Envs[0].PushedVersion)
+		assert.Equal(t, 0, saved.Envs[1].PushedVersion)
+
+		f.envService.failPut["production"] = false
+		assert.NoError(t, finishRotation(f.svc, saved, f.oldSecret, f.newSecret))
+		assert.Equal(t, "A=1\n", f.envService.content(t, "default", f.newSecret))
+		assert.Equal(t, "B=2\n", f.envService.content(t, "production", f.newSecret))
+		assert.Equal(t, []models.Secret{f.newSecret}, f.saved)
+	})
+
+	t.Run("rollback_returns_envs_and_store_to_old_key", func(t *testing.T) {
+		f := setupRotation(t)
+		assert.NoError(t, f.svc.reencrypt(f.journal, f.oldSecret, f.newSecret))
+		assert.NoError(t, f.svc.switchKey(f.journal, f.newSecret))
+
+		assert.NoError(t, f.svc.rollback(f.journal, f.oldSecret, f.newSecret))
+
+		assert.Equal(t, "A=1\n", f.envService.content(t, "default", f.oldSecret))
+		assert.Equal(t, "B=2\n", f.envService.content(t, "production", f.oldSecret))
+		assert.Equal(t, []models.Secret{f.newSecret, f.oldSecret}, f.saved)
+		assert.False(t, f.journal.Switched)
+	})
+}
+
+func TestJournalSecrets(t *testing.T) {
+	oldSecret := testSecret(1, "old-secret-old-secret-old-secret-old-secret")
+	newSecret := testSecret(2, "new-secret-new-secret-new-secret-new-secret")
+	j, err := newJournal("org-1", "project-1", 0, oldSecret, newSecret)
+	assert.NoError(t, err)
+
+	for _, current := range []models.Secret{oldSecret, newSecret} {
+		gotOld, gotNew, err := j.secrets(current)
+		assert.NoError(t, err)
+		assert.Equal(t, oldSecret, gotOld)
+		assert.Equal(t, newSecret, gotNew)
+	}
+
+	_, _, err = j.secrets(testSecret(3, "other"))
+	assert.ErrorContains(t, err, "neither the old key 1 nor the new key 2")
+}
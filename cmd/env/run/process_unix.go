@@ -0,0 +1,85 @@
+//go:build unix
+
+package run
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// terminal is the controlling terminal hx was started in the foreground of.
+type terminal struct {
+	fd   int
+	pgrp int
+}
+
+// foregroundTerminal returns the terminal on stdin when hx's process group
+// owns it, or nil when hx runs without one or in the background.
+func foregroundTerminal() *terminal {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil
+	}
+	pgrp := unix.Getpgrp()
+	if fg, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP); err != nil || fg != pgrp {
+		return nil
+	}
+	return &terminal{fd: fd, pgrp: pgrp}
+}
+
+// configureCommand starts the child in its own process group so signals can
+// be forwarded to everything it spawns. On a terminal that group is put in
+// the foreground, so the child can read stdin and receives Ctrl-C directly.
+func configureCommand(cmd *exec.Cmd, tty *terminal) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if tty != nil {
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = tty.fd
+	}
+}
+
+// reclaim moves hx's process group back to the foreground after the child
+// exits.
+func (t *terminal) reclaim() {
+	if t == nil {
+		return
+	}
+	// Changing the foreground group from the background raises SIGTTOU
+	// unless it is ignored.
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	_ = unix.IoctlSetPointerInt(t.fd, unix.TIOCSPGRP, t.pgrp)
+}
+
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) error {
+	return syscall.Kill(-cmd.Process.Pid, sig.(syscall.Signal))
+}
+
+// terminateChild asks the child's process group to exit.
+func terminateChild(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+func killChild(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+func isStopSignal(sig os.Signal) bool {
+	return sig != syscall.SIGHUP
+}
+
+// exitCode follows the shell convention of 128+n for a child killed by
+// signal n.
+func exitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
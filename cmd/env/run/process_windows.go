@@ -0,0 +1,50 @@
+//go:build windows
+
+package run
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// terminal is unused on Windows, where console Ctrl-C events already reach
+// every process attached to the console.
+type terminal struct{}
+
+func foregroundTerminal() *terminal {
+	return nil
+}
+
+func configureCommand(cmd *exec.Cmd, tty *terminal) {}
+
+func (t *terminal) reclaim() {}
+
+// forwardSignal leaves Ctrl-C to the console, which delivers it to the child
+// too, and kills the child for anything else.
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) error {
+	if sig == os.Interrupt {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// terminateChild kills the child, as Windows has no equivalent of SIGTERM
+// for console programs.
+func terminateChild(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func killChild(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func isStopSignal(sig os.Signal) bool {
+	return true
+}
+
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
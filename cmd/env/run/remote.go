@@ -0,0 +1,41 @@
+package run
+
+import (
+	"fmt"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+)
+
+// loadRemoteEnv fetches and decrypts the latest version of envName for the
+// current app. The plaintext only ever lives in memory.
+func loadRemoteEnv(envName string) ([]env.Variable, error) {
+	remote, err := env.LoadRemote(envName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return remoteVariables(envName, remote)
+}
+
+// remoteVariables parses a remote version, marking each variable with the
+// version it came from.
+func remoteVariables(envName string, remote env.RemoteEnv) ([]env.Variable, error) {
+	vars, err := env.Parse(remote.Content)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid remote %s environment: %s", envName, err)
+	}
+
+	source := fmt.Sprintf("remote %s", envName)
+	if remote.Version != nil {
+		source = fmt.Sprintf("remote %s v%d", envName, *remote.Version)
+	}
+	for i := range vars {
+		vars[i].Source = source
+	}
+
+	if flags.VerboseFlag {
+		printer.Info(fmt.Sprintf("Loaded %s environment", source))
+	}
+	return vars, nil
+}
@@ -0,0 +1,36 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteVariables(t *testing.T) {
+	printer = cprint.NewCPrinter(false)
+
+	t.Run("marks_variables_with_the_version", func(t *testing.T) {
+		version := 3
+		remote := env.RemoteEnv{
+			Env:     models.Env{Version: &version},
+			Content: "HOST=db.internal\nURL=postgres://${HOST}/main\n",
+		}
+
+		vars, err := remoteVariables("production", remote)
+
+		assert.NoError(t, err)
+		assert.Len(t, vars, 2)
+		assert.Equal(t, "HOST", vars[0].Key)
+		assert.Equal(t, "db.internal", vars[0].Value)
+		assert.Equal(t, "remote production v3", vars[0].Source)
+	})
+
+	t.Run("invalid_content", func(t *testing.T) {
+		_, err := remoteVariables("production", env.RemoteEnv{Content: "not a dotenv line\n"})
+
+		assert.ErrorContains(t, err, "Invalid remote production environment")
+	})
+}
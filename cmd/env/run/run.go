@@ -1,10 +1,9 @@
 package run
 
 import (
-	"bufio"
 	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/Hyphen/cli/internal/config"
@@ -15,7 +14,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var printer *cprint.CPrinter
+var (
+	noInterpolate    bool
+	explain          bool
+	remoteEnv        string
+	restartPolicy    string
+	maxRestarts      int
+	watch            bool
+	outputFormatFlag string
+	printer          *cprint.CPrinter
+)
 
 var RunCmd = &cobra.Command{
 	Use:   "run [environment] -- [command]",
@@ -26,13 +34,52 @@ The run command executes your application with the specified environment variabl
 Usage:
   hyphen env run [environment] -- [command]
 
+Variables from .env, .env.local and .env.[environment] are merged, with later
+files taking precedence. References such as ${VAR}, $VAR and ${VAR:-default}
+are expanded across the merged files and your shell environment; use
+--no-interpolate to pass values through unchanged.
+
+The command runs with your terminal attached. Signals such as SIGINT and
+SIGTERM are forwarded to its whole process group, and hx exits with the
+command's exit code, so it can serve as a container entrypoint. With
+--restart on-failure the command is restarted with backoff whenever it exits
+with a non-zero code, until it succeeds, hx is stopped or --max-restarts is
+reached.
+
+With --watch the env files are watched while the command runs. When they
+resolve to different variables, for example after a pull, the command is
+stopped with SIGTERM and started again with the new environment.
+
+Use --remote to run with the latest pushed version of an environment instead
+of local files. The variables are fetched, decrypted and handed to the command
+in memory; no plaintext is written to disk, which suits CI and shared machines.
+
+Use --explain to see where each variable comes from instead of running the
+command. It lists the file and line that won for every key and the layers it
+overrode, including your shell environment, with values masked. Add
+--output json for a machine-readable report.
+
 Examples:
   hyphen env run production -- go run main.go
   hyphen env run staging -- node server.js
   hyphen env run -- go run main.go (uses default environment)
+  hyphen env run --remote production -- node server.js
+  hyphen env run production --restart on-failure -- ./worker
+  hyphen env run --watch production -- node server.js
+  hyphen env run production --explain
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		if outputFormatFlag != "" && !explain {
+			return errors.New("--output is only supported together with --explain")
+		}
+		printer.SetFormat(outputFormatFlag)
+		if watch && (remoteEnv != "" || explain) {
+			return errors.New("--watch can't be combined with --remote or --explain")
+		}
+		if restartPolicy != restartNever && restartPolicy != restartOnFailure {
+			return fmt.Errorf("invalid --restart policy %q, expected %q or %q", restartPolicy, restartNever, restartOnFailure)
+		}
 
 		var envName string
 		var childCommand []string
@@ -48,38 +95,140 @@ Examples:
 			childCommand = args[separatorIndex:]
 		}
 
-		if len(childCommand) == 0 {
-			return errors.New("No command specified")
+		if separatorIndex == -1 && explain && len(args) > 0 {
+			// With --explain no command is needed, so a lone argument is the environment
+			envName = args[0]
+			childCommand = args[1:]
 		}
 
-		config, err := config.RestoreConfig()
-		if err != nil {
-			return errors.Wrap(err, "Failed to restore manifest")
+		if len(childCommand) == 0 && !explain {
+			return errors.New("No command specified")
 		}
 
-		mergedEnvVars, err := loadAndMergeEnvFiles(envName, config)
+		var mergedVars []env.Variable
+		var err error
+		if remoteEnv != "" {
+			if envName != "default" {
+				return errors.New("Specify the environment either as an argument or with --remote, not both")
+			}
+			envName = remoteEnv
+			mergedVars, err = loadRemoteVariables(envName)
+		} else {
+			mergedVars, err = loadLocalVariables(envName)
+		}
 		if err != nil {
 			return err
 		}
 
-		if err := runCommandWithEnv(childCommand, mergedEnvVars); err != nil {
-			return errors.Wrap(err, "Command execution failed")
+		if explain {
+			return printExplanation(envName, env.Explain(mergedVars, os.LookupEnv))
 		}
-		return nil
+
+		s := &supervisor{
+			command:     childCommand,
+			environ:     env.EffectiveEnviron(mergedVars),
+			restart:     restartPolicy,
+			maxRestarts: maxRestarts,
+		}
+		if watch {
+			w, err := newEnvWatcher(envName, s.environ)
+			if err != nil {
+				return err
+			}
+			stop := make(chan struct{})
+			defer close(stop)
+			s.reload = w.watch(stop)
+		}
+		return s.run()
 	},
 }
 
-func loadAndMergeEnvFiles(envName string, config config.Config) ([]string, error) {
-	var mergedVars []string
+func init() {
+	RunCmd.Flags().BoolVar(&noInterpolate, "no-interpolate", false, "Pass values through without expanding ${VAR} references")
+	RunCmd.Flags().StringVar(&remoteEnv, "remote", "", "Run with the latest pushed version of this environment, decrypted in memory instead of read from local files")
+	RunCmd.Flags().StringVar(&restartPolicy, "restart", restartNever, "Restart policy for the command: \"no\" or \"on-failure\"")
+	RunCmd.Flags().IntVar(&maxRestarts, "max-restarts", 0, "Give up after this many restarts with --restart on-failure (0 means no limit)")
+	RunCmd.Flags().BoolVar(&watch, "watch", false, "Restart the command when the env files change")
+	RunCmd.Flags().BoolVar(&explain, "explain", false, "Show where each variable comes from instead of running the command")
+	RunCmd.Flags().StringVar(&outputFormatFlag, "output", "", "Output format for --explain. Set to \"json\" to emit a JSON object with environment and a variables array of key, value (masked), source, line and overrides.")
+}
+
+func loadLocalVariables(envName string) ([]env.Variable, error) {
+	config, err := config.RestoreConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to restore manifest")
+	}
+
+	mergedVars, err := loadAndMergeEnvFiles(envName, config)
+	if err != nil {
+		return nil, err
+	}
+	mergedVars, err = withSchemaDefaults(envName, mergedVars)
+	if err != nil {
+		return nil, err
+	}
+	return interpolate(mergedVars)
+}
+
+func loadRemoteVariables(envName string) ([]env.Variable, error) {
+	vars, err := loadRemoteEnv(envName)
+	if err != nil {
+		return nil, err
+	}
+	vars, err = withSchemaDefaults(envName, vars)
+	if err != nil {
+		return nil, err
+	}
+	return interpolate(vars)
+}
+
+// withSchemaDefaults puts the .env.schema defaults of variables that neither
+// vars nor the host environment define in front of vars, so any definition
+// overrides them.
+func withSchemaDefaults(envName string, vars []env.Variable) ([]env.Variable, error) {
+	schema, err := env.LoadSchema(env.SchemaFileName)
+	if err != nil || schema == nil {
+		return vars, err
+	}
+
+	defined := make(map[string]string, len(vars))
+	for _, v := range vars {
+		defined[v.Key] = v.Value
+	}
+	for key := range schema.Variables {
+		if value, ok := os.LookupEnv(key); ok {
+			defined[key] = value
+		}
+	}
+	return append(schema.Defaults(envName, defined), vars...), nil
+}
+
+// interpolate expands references in vars unless --no-interpolate is set.
+func interpolate(vars []env.Variable) ([]env.Variable, error) {
+	if noInterpolate {
+		return vars, nil
+	}
+
+	resolved, err := env.Interpolate(vars, os.LookupEnv)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to expand variables: %s", err)
+	}
+	return resolved, nil
+}
+
+// loadAndMergeEnvFiles returns the definitions from every env file that
+// applies to envName, lowest precedence first.
+func loadAndMergeEnvFiles(envName string, config config.Config) ([]env.Variable, error) {
+	var mergedVars []env.Variable
 
 	// Load .env file (default)
-	if err := loadAndAppendEnv("default", config, &mergedVars); err != nil && envName == "default" {
-		return nil, err // Return error if default is specifically requested and doesn't exist
+	if err := loadAndAppendEnv("default", config, &mergedVars); err != nil && (envName == "default" || !errors.Is(err, fs.ErrNotExist)) {
+		return nil, err // Return error if default is specifically requested and doesn't exist, or is invalid
 	}
 
 	// Load .env.local file (if exists)
-	if err := loadAndAppendEnv("local", config, &mergedVars); err != nil && envName == "local" {
-		return nil, err // Return error if local is specifically requested and doesn't exist
+	if err := loadAndAppendEnv("local", config, &mergedVars); err != nil && (envName == "local" || !errors.Is(err, fs.ErrNotExist)) {
+		return nil, err // Return error if local is specifically requested and doesn't exist, or is invalid
 	}
 
 	// Load .env.<environment> file (if provided)
@@ -92,22 +241,28 @@ func loadAndMergeEnvFiles(envName string, config config.Config) ([]string, error
 	return mergedVars, nil
 }
 
-func loadAndAppendEnv(envName string, config config.Config, mergedVars *[]string) error {
+func loadAndAppendEnv(envName string, config config.Config, mergedVars *[]env.Variable) error {
 	envContents, err := env.GetLocalEnvContents(envName)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return errors.Wrap(err, fmt.Sprintf("%s env file not found", envName))
 		}
 		return errors.Wrap(err, fmt.Sprintf("Error loading %s env file", envName))
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(envContents))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if env.IsEnvVar(line) {
-			*mergedVars = append(*mergedVars, line)
-		}
+	vars, err := env.Parse(envContents)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid %s env file: %s", envName, err)
+	}
+
+	fileName, err := env.GetFileName(envName)
+	if err != nil {
+		return err
 	}
+	for i := range vars {
+		vars[i].Source = fileName
+	}
+	*mergedVars = append(*mergedVars, vars...)
 
 	if flags.VerboseFlag {
 		printer.Info(fmt.Sprintf("Loaded and appended %s environment", envName))
@@ -115,15 +270,30 @@ func loadAndAppendEnv(envName string, config config.Config, mergedVars *[]string
 	return nil
 }
 
-func runCommandWithEnv(command []string, envVars []string) error {
-	cmd := exec.Command(command[0], command[1:]...)
-	cmd.Env = append(os.Environ(), envVars...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+func printExplanation(envName string, explanations []env.Explanation) error {
+	if printer.IsJSON() {
+		return printer.Emit(map[string]any{
+			"environment": envName,
+			"variables":   explanations,
+		})
+	}
 
-	if flags.VerboseFlag {
-		printer.Info(fmt.Sprintf("Running command with %s environment", command[0]))
-		printer.Info(fmt.Sprintf("Executing command: %s", strings.Join(command, " ")))
+	if len(explanations) == 0 {
+		printer.Info(fmt.Sprintf("No variables are defined for the %s environment", envName))
+		return nil
 	}
-	return cmd.Run()
+
+	printer.PrintHeader(fmt.Sprintf("Variables for the %s environment", envName))
+	for _, e := range explanations {
+		value := e.Value
+		if value == "" {
+			value = "(empty)"
+		}
+		detail := fmt.Sprintf("%s from %s:%d", value, e.Source, e.Line)
+		if len(e.Overrides) > 0 {
+			detail += fmt.Sprintf(", overrides %s", strings.Join(e.Overrides, ", "))
+		}
+		printer.PrintDetail(e.Key, detail)
+	}
+	return nil
 }
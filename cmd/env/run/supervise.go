@@ -0,0 +1,172 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+)
+
+// Restart policies accepted by --restart.
+const (
+	restartNever     = "no"
+	restartOnFailure = "on-failure"
+)
+
+const (
+	minRestartDelay = time.Second
+	maxRestartDelay = 30 * time.Second
+	// A child that stays up this long resets the restart backoff.
+	stableRunTime = 10 * time.Second
+	// stopGracePeriod is how long a child may take to exit after SIGTERM
+	// before it is killed.
+	stopGracePeriod = 10 * time.Second
+)
+
+// supervisor runs a child command with stdio attached, forwards signals to
+// it and optionally restarts it when it fails.
+type supervisor struct {
+	command     []string
+	environ     []string
+	restart     string
+	maxRestarts int
+	// reload, when set, delivers a new environment whenever the watched env
+	// files resolve to different variables. The child is restarted with it.
+	reload <-chan []string
+}
+
+// exitReason tells why a run of the child ended.
+type exitReason int
+
+const (
+	childExited exitReason = iota
+	stopRequested
+	reloadRequested
+)
+
+// run supervises the child until it exits for good. A non-zero exit is
+// returned as an *errors.ExitError carrying the child's exit code.
+func (s *supervisor) run() error {
+	signals := make(chan os.Signal, 4)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	tty := foregroundTerminal()
+	restarts := 0
+	delay := minRestartDelay
+
+	for {
+		started := time.Now()
+		code, reason, err := s.runOnce(tty, signals)
+		if err != nil {
+			return err
+		}
+
+		switch reason {
+		case stopRequested:
+			return exitResult(code)
+		case reloadRequested:
+			printer.Info(fmt.Sprintf("Environment changed, restarting %s", s.command[0]))
+			restarts = 0
+			delay = minRestartDelay
+			continue
+		}
+
+		if code == 0 || s.restart != restartOnFailure || (s.maxRestarts > 0 && restarts >= s.maxRestarts) {
+			if s.reload == nil {
+				return exitResult(code)
+			}
+
+			printer.Info(fmt.Sprintf("%s exited with code %d, waiting for environment changes", s.command[0], code))
+			select {
+			case <-signals:
+				return exitResult(code)
+			case s.environ = <-s.reload:
+			}
+			restarts = 0
+			delay = minRestartDelay
+			continue
+		}
+
+		if time.Since(started) >= stableRunTime {
+			delay = minRestartDelay
+		}
+		printer.Warning(fmt.Sprintf("%s exited with code %d, restarting in %s", s.command[0], code, delay))
+
+		select {
+		case <-signals:
+			return exitResult(code)
+		case s.environ = <-s.reload:
+		case <-time.After(delay):
+		}
+
+		restarts++
+		delay = min(delay*2, maxRestartDelay)
+	}
+}
+
+// runOnce starts the child and waits for it to exit, forwarding any signals
+// received meanwhile. A new environment from reload stops the child
+// gracefully so run can start it again.
+func (s *supervisor) runOnce(tty *terminal, signals <-chan os.Signal) (int, exitReason, error) {
+	cmd := exec.Command(s.command[0], s.command[1:]...)
+	cmd.Env = append(os.Environ(), s.environ...)
+	// The CLI works from the app root; the child runs where it was invoked.
+	cmd.Dir = config.InvocationDirectory()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	configureCommand(cmd, tty)
+
+	if flags.VerboseFlag {
+		printer.Info(fmt.Sprintf("Executing command: %s", strings.Join(s.command, " ")))
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 0, childExited, errors.Wrapf(err, "Failed to start %s: %s", s.command[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	reason := childExited
+	var kill <-chan time.Time
+	for {
+		select {
+		case sig := <-signals:
+			if isStopSignal(sig) {
+				reason = stopRequested
+			}
+			if err := forwardSignal(cmd, sig); err != nil && flags.VerboseFlag {
+				printer.Info(fmt.Sprintf("Failed to forward %s: %s", sig, err))
+			}
+		case s.environ = <-s.reload:
+			if reason == childExited {
+				reason = reloadRequested
+				_ = terminateChild(cmd)
+				kill = time.After(stopGracePeriod)
+			}
+		case <-kill:
+			_ = killChild(cmd)
+		case waitErr := <-done:
+			tty.reclaim()
+			if cmd.ProcessState == nil {
+				return 0, reason, errors.Wrapf(waitErr, "Command execution failed: %s", waitErr)
+			}
+			return exitCode(cmd.ProcessState), reason, nil
+		}
+	}
+}
+
+func exitResult(code int) error {
+	if code == 0 {
+		return nil
+	}
+	return &errors.ExitError{Code: code}
+}
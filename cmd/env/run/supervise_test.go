@@ -0,0 +1,57 @@
+//go:build unix
+
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupervisor(t *testing.T) {
+	printer = cprint.NewCPrinter(false)
+
+	t.Run("success", func(t *testing.T) {
+		s := &supervisor{command: []string{"sh", "-c", "test \"$GREETING\" = hello"}, environ: []string{"GREETING=hello"}, restart: restartNever}
+
+		assert.NoError(t, s.run())
+	})
+
+	t.Run("propagates_exit_code", func(t *testing.T) {
+		s := &supervisor{command: []string{"sh", "-c", "exit 7"}, restart: restartNever}
+
+		var exitErr *errors.ExitError
+		assert.True(t, errors.As(s.run(), &exitErr))
+		assert.Equal(t, 7, exitErr.Code)
+	})
+
+	t.Run("killed_by_signal", func(t *testing.T) {
+		s := &supervisor{command: []string{"sh", "-c", "kill -TERM $$"}, restart: restartNever}
+
+		var exitErr *errors.ExitError
+		assert.True(t, errors.As(s.run(), &exitErr))
+		assert.Equal(t, 143, exitErr.Code)
+	})
+
+	t.Run("restarts_on_failure_up_to_limit", func(t *testing.T) {
+		counter := filepath.Join(t.TempDir(), "runs")
+		s := &supervisor{command: []string{"sh", "-c", "echo run >> " + counter + "; exit 2"}, restart: restartOnFailure, maxRestarts: 1}
+
+		var exitErr *errors.ExitError
+		assert.True(t, errors.As(s.run(), &exitErr))
+		assert.Equal(t, 2, exitErr.Code)
+		runs, err := os.ReadFile(counter)
+		assert.NoError(t, err)
+		assert.Equal(t, "run\nrun\n", string(runs))
+	})
+
+	t.Run("missing_command", func(t *testing.T) {
+		s := &supervisor{command: []string{"hx-command-that-does-not-exist"}, restart: restartNever}
+
+		assert.ErrorContains(t, s.run(), "Failed to start hx-command-that-does-not-exist")
+	})
+}
@@ -0,0 +1,128 @@
+package run
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Hyphen/cli/internal/env"
+)
+
+const watchInterval = time.Second
+
+// fileStamp is what the watcher compares to notice that a file changed.
+type fileStamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.exists == other.exists && s.modTime.Equal(other.modTime) && s.size == other.size
+}
+
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
+
+// envWatcher polls the env files merged for an environment, including ones
+// that don't exist yet, and reports when they resolve to new variables.
+type envWatcher struct {
+	paths  []string
+	stamps map[string]fileStamp
+	// load resolves the files into KEY=VALUE pairs.
+	load    func() ([]string, error)
+	environ []string
+}
+
+func newEnvWatcher(envName string, environ []string) (*envWatcher, error) {
+	var paths []string
+	for _, name := range []string{"default", "local", envName} {
+		path, err := env.GetFileName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+
+	w := &envWatcher{
+		paths: paths,
+		load: func() ([]string, error) {
+			vars, err := loadLocalVariables(envName)
+			if err != nil {
+				return nil, err
+			}
+			return env.EffectiveEnviron(vars), nil
+		},
+		environ: environ,
+	}
+	w.stamps = w.stampAll()
+	return w, nil
+}
+
+func (w *envWatcher) stampAll() map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(w.paths))
+	for _, path := range w.paths {
+		stamps[path] = stampFile(path)
+	}
+	return stamps
+}
+
+// poll returns the new environment when the files changed on disk and
+// resolve to different variables than last time. Files that fail to load
+// are reported and skipped until they are fixed.
+func (w *envWatcher) poll() ([]string, bool) {
+	stamps := w.stampAll()
+	if maps.EqualFunc(stamps, w.stamps, fileStamp.equal) {
+		return nil, false
+	}
+	w.stamps = stamps
+
+	environ, err := w.load()
+	if err != nil {
+		printer.Warning(fmt.Sprintf("Not restarting, env files are invalid: %s", err))
+		return nil, false
+	}
+	if slices.Equal(environ, w.environ) {
+		return nil, false
+	}
+	w.environ = environ
+	return environ, true
+}
+
+// watch polls until stop is closed, delivering the latest environment on the
+// returned channel. A pending environment that hasn't been picked up yet is
+// replaced by a newer one.
+func (w *envWatcher) watch(stop <-chan struct{}) <-chan []string {
+	changes := make(chan []string, 1)
+	go func() {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			environ, changed := w.poll()
+			if !changed {
+				continue
+			}
+			select {
+			case <-changes:
+			default:
+			}
+			changes <- environ
+		}
+	}()
+	return changes
+}
@@ -0,0 +1,59 @@
+package run
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/testutil"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvWatcherPoll(t *testing.T) {
+	printer = cprint.NewCPrinter(false)
+	testutil.ChdirTemp(t)
+
+	assert.NoError(t, os.WriteFile(".env", []byte("A=1\n"), 0600))
+	w, err := newEnvWatcher("staging", []string{"A=1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".env", ".env.local", ".env.staging"}, w.paths)
+	w.load = func() ([]string, error) {
+		var environ []string
+		for _, path := range w.paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			parsed, err := env.Parse(string(content))
+			if err != nil {
+				return nil, err
+			}
+			environ = append(environ, env.EffectiveEnviron(parsed)...)
+		}
+		return environ, nil
+	}
+
+	touch := func(path, content string) {
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		future := time.Now().Add(time.Minute)
+		assert.NoError(t, os.Chtimes(path, future, future))
+	}
+
+	_, changed := w.poll()
+	assert.False(t, changed, "nothing changed on disk")
+
+	touch(".env", "# comment\nA=1\n")
+	_, changed = w.poll()
+	assert.False(t, changed, "same variables")
+
+	touch(".env.staging", "B=2\n")
+	environ, changed := w.poll()
+	assert.True(t, changed, "a new file appeared")
+	assert.Equal(t, []string{"A=1", "B=2"}, environ)
+
+	assert.NoError(t, os.WriteFile(".env.staging", []byte("B=\"broken\n"), 0600))
+	_, changed = w.poll()
+	assert.False(t, changed, "invalid files are skipped")
+}
@@ -0,0 +1,133 @@
+package validate
+
+import (
+	"fmt"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remote  bool
+	printer *cprint.CPrinter
+)
+
+var ValidateCmd = &cobra.Command{
+	Use:   "validate [environment]",
+	Short: "Check environments against .env.schema",
+	Long: `
+The validate command checks environments against the .env.schema file in the
+current directory, reporting missing required variables and values of the
+wrong type. Values are never printed.
+
+Without an environment every local .env file is checked. Use --remote to check
+the latest pushed version of an environment instead of the local file.
+
+A schema is a YAML file listing the expected variables:
+
+  variables:
+    DATABASE_URL:
+      required: true
+      type: url
+      environments: [production, staging]
+    LOG_LEVEL:
+      type: enum
+      values: [debug, info, warn, error]
+      default: info
+
+Types are string (the default), int, bool, url, enum (with values) and regex
+(with pattern). A rule without environments applies to all of them, and a
+required variable with a default may be left unset.
+
+Examples:
+  hyphen env validate
+  hyphen env validate production
+  hyphen env validate production --remote
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunValidate(args)
+	},
+}
+
+func init() {
+	ValidateCmd.Flags().BoolVar(&remote, "remote", false, "Validate the latest remote version instead of the local file")
+}
+
+func RunValidate(args []string) error {
+	schema, err := env.LoadSchema(env.SchemaFileName)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return fmt.Errorf("no %s found in the current directory", env.SchemaFileName)
+	}
+
+	var envNames []string
+	switch {
+	case len(args) == 1:
+		envName, err := env.GetEnvName(args[0])
+		if err != nil {
+			return err
+		}
+		envNames = []string{envName}
+	case remote:
+		return errors.New("Specify the environment to validate with --remote")
+	default:
+		files, err := env.GetEnvsInDirectory()
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			envName, err := env.GetEnvNameFromFile(file)
+			if err != nil {
+				return err
+			}
+			envNames = append(envNames, envName)
+		}
+		if len(envNames) == 0 {
+			return errors.New("No .env files found in the app directory")
+		}
+	}
+
+	invalid := 0
+	for _, envName := range envNames {
+		var content, label string
+		if remote {
+			var r env.RemoteEnv
+			r, err = env.LoadRemote(envName, nil)
+			content, label = r.Content, r.Label(envName)
+		} else {
+			content, label, err = env.LoadLocal(envName)
+		}
+		if err != nil {
+			return err
+		}
+
+		vars, err := env.Variables(content)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid .env file: %w", label, err)
+		}
+
+		violations := schema.Validate(envName, vars)
+		if len(violations) == 0 {
+			printer.Success(fmt.Sprintf("%s matches %s", label, env.SchemaFileName))
+			continue
+		}
+
+		invalid++
+		printer.Warning(fmt.Sprintf("%s does not match %s:", label, env.SchemaFileName))
+		for _, v := range violations {
+			printer.Print(fmt.Sprintf("  - %s", v))
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d environments failed validation", invalid, len(envNames))
+	}
+	return nil
+}
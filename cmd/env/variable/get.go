@@ -0,0 +1,62 @@
+package variable
+
+import (
+	"fmt"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+var GetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print a variable from a remote environment",
+	Long: `
+The get command fetches and decrypts the latest version of a remote environment
+and prints the value of a single variable to stdout.
+
+Examples:
+  hyphen env get DATABASE_URL -e production
+  export API_URL=$(hyphen env get API_URL -e staging)
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunGet(args[0])
+	},
+}
+
+func RunGet(key string) error {
+	if err := env.ValidateKey(key); err != nil {
+		return err
+	}
+
+	t, err := loadTarget()
+	if err != nil {
+		return err
+	}
+
+	service := newService(env.NewService(), nil)
+
+	content, remote, err := service.fetch(t)
+	if err != nil {
+		return err
+	}
+	if remote == nil {
+		return fmt.Errorf("environment '%s' has no variables yet", t.envName)
+	}
+
+	vars, err := env.Variables(content)
+	if err != nil {
+		return fmt.Errorf("remote environment '%s' is not a valid .env file: %w", t.envName, err)
+	}
+
+	value, ok := vars[key]
+	if !ok {
+		return fmt.Errorf("variable %s is not set in environment '%s'", key, t.envName)
+	}
+
+	fmt.Println(value)
+	return nil
+}
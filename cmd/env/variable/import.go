@@ -0,0 +1,150 @@
+package variable
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/Hyphen/cli/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFormat string
+	importMerge  bool
+	importDryRun bool
+)
+
+var ImportCmd = &cobra.Command{
+	Use:   "import FILE --format FORMAT",
+	Short: "Import variables from another format into a remote environment",
+	Long: fmt.Sprintf(`
+The import command converts variables from another tool's format into a .env
+file and pushes it as the next version of a remote environment.
+
+Formats: %s
+
+json and yaml files must be a flat object of keys and values. compose reads a
+docker compose env_file. k8s-secret and k8s-configmap read the first manifest
+of that kind, base64-decoding Secret data and including stringData.
+
+Before anything is pushed you see which keys will be added, changed or removed
+and are asked to confirm. By default the imported file replaces the
+environment; use --merge to keep variables the file doesn't mention.
+
+Examples:
+  hyphen env import secret.yaml --format k8s-secret -e production
+  hyphen env import config.json --format json -e staging --merge
+  hyphen env import .env.docker --format compose -e staging --dry-run
+`, strings.Join(env.ImportFormats, ", ")),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunImport(cmd, args[0])
+	},
+}
+
+func init() {
+	ImportCmd.Flags().StringVar(&importFormat, "format", "", fmt.Sprintf("Input format (%s)", strings.Join(env.ImportFormats, ", ")))
+	ImportCmd.Flags().BoolVar(&importMerge, "merge", false, "Keep remote variables that the imported file doesn't define")
+	ImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show what would change without pushing")
+	addSkipValidationFlag(ImportCmd)
+}
+
+func RunImport(cmd *cobra.Command, file string) error {
+	if importFormat == "" {
+		return fmt.Errorf("--format is required, expected one of: %s", strings.Join(env.ImportFormats, ", "))
+	}
+
+	data, err := os.ReadFile(config.ResolveInvocationPath(file))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	imported, err := env.Convert(importFormat, data)
+	if err != nil {
+		return fmt.Errorf("failed to import %s as %s: %w", file, importFormat, err)
+	}
+
+	t, err := loadTarget()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Restore()
+	if err != nil {
+		return err
+	}
+	service := newService(env.NewService(), db)
+	if err := service.useSchema(); err != nil {
+		return err
+	}
+
+	content, remote, err := service.fetch(t)
+	if err != nil {
+		return err
+	}
+
+	current, err := env.Variables(content)
+	if err != nil {
+		return fmt.Errorf("remote environment '%s' is not a valid .env file: %w", t.envName, err)
+	}
+
+	newContent, err := importedContent(content, imported, importMerge)
+	if err != nil {
+		return err
+	}
+
+	updated, err := env.Variables(newContent)
+	if err != nil {
+		return err
+	}
+
+	changes := env.DiffVariables(current, updated)
+	if len(changes) == 0 {
+		printer.Info(fmt.Sprintf("Environment '%s' already matches %s", t.envName, file))
+		return nil
+	}
+
+	removals := printChangePreview(t.envName, changes)
+	if importDryRun {
+		return nil
+	}
+
+	response := prompt.PromptYesNo(cmd, fmt.Sprintf("Push these changes to environment '%s'?", t.envName), removals == 0)
+	if !response.Confirmed {
+		printer.Info("Import cancelled")
+		return nil
+	}
+
+	version, err := service.publish(t, newContent, remote)
+	if err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Imported %d variables from %s into environment '%s' (version %d)", len(imported), file, t.envName, version))
+	return nil
+}
+
+// importedContent returns the .env content to push. With merge the imported
+// variables are set on top of the remote content, keeping its comments and
+// other variables; otherwise they replace it.
+func importedContent(content string, imported []env.Variable, merge bool) (string, error) {
+	if !merge {
+		return env.Serialize(imported), nil
+	}
+
+	var err error
+	for _, v := range imported {
+		content, err = env.SetVariable(content, v.Key, v.Value)
+		if err != nil {
+			return "", err
+		}
+	}
+	return content, nil
+}
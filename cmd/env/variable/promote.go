@@ -0,0 +1,193 @@
+package variable
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/Hyphen/cli/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteKeys    []string
+	promoteAll     bool
+	promoteExclude []string
+)
+
+var PromoteCmd = &cobra.Command{
+	Use:   "promote SOURCE TARGET [--keys KEY,... | --all]",
+	Short: "Copy variables from one remote environment to another",
+	Long: `
+The promote command copies variables from the latest version of one remote
+environment into another, such as a vetted set of keys from staging to
+production, and pushes the result as the target's next version.
+
+Choose the variables with --keys, or copy every variable with --all. Use
+--exclude to leave some out. Variables the target has that aren't copied are
+kept as they are.
+
+Before anything is pushed you see which keys will be added or changed, with
+their values masked, and are asked to confirm.
+
+Examples:
+  hyphen env promote staging production --keys API_URL,FEATURE_X
+  hyphen env promote staging production --all --exclude DATABASE_URL
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunPromote(cmd, args[0], args[1])
+	},
+}
+
+func init() {
+	PromoteCmd.Flags().StringSliceVar(&promoteKeys, "keys", nil, "Variables to copy")
+	PromoteCmd.Flags().BoolVar(&promoteAll, "all", false, "Copy every variable")
+	PromoteCmd.Flags().StringSliceVar(&promoteExclude, "exclude", nil, "Variables not to copy")
+	PromoteCmd.MarkFlagsMutuallyExclusive("keys", "all")
+	addSkipValidationFlag(PromoteCmd)
+}
+
+func RunPromote(cmd *cobra.Command, source, destination string) error {
+	if len(promoteKeys) == 0 && !promoteAll {
+		return fmt.Errorf("choose the variables to promote with --keys or --all")
+	}
+
+	sourceName, err := env.GetEnvName(source)
+	if err != nil {
+		return err
+	}
+	destinationName, err := env.GetEnvName(destination)
+	if err != nil {
+		return err
+	}
+	if sourceName == destinationName {
+		return fmt.Errorf("source and target environments are both '%s'", sourceName)
+	}
+
+	t, err := loadTarget()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Restore()
+	if err != nil {
+		return err
+	}
+	service := newService(env.NewService(), db)
+	if err := service.useSchema(); err != nil {
+		return err
+	}
+
+	from := t
+	from.envName = sourceName
+	sourceContent, sourceRemote, err := service.fetch(from)
+	if err != nil {
+		return err
+	}
+	if sourceRemote == nil {
+		return fmt.Errorf("nothing has been pushed to the %s environment yet", sourceName)
+	}
+
+	t.envName = destinationName
+	content, remote, err := service.fetch(t)
+	if err != nil {
+		return err
+	}
+
+	newContent, promoted, err := promotedContent(content, sourceContent, promoteKeys, promoteExclude)
+	if err != nil {
+		return err
+	}
+
+	current, err := env.Variables(content)
+	if err != nil {
+		return fmt.Errorf("remote environment '%s' is not a valid .env file: %w", t.envName, err)
+	}
+	updated, err := env.Variables(newContent)
+	if err != nil {
+		return err
+	}
+
+	changes := env.DiffVariables(current, updated)
+	if len(changes) == 0 {
+		printer.Info(fmt.Sprintf("Environment '%s' already has the same values as '%s' for %s", t.envName, sourceName, strings.Join(promoted, ", ")))
+		return nil
+	}
+
+	printer.PrintHeader(fmt.Sprintf("Promoting from '%s' to '%s'", sourceName, t.envName))
+	for _, c := range changes {
+		switch c.Type {
+		case env.ChangeAdded:
+			printer.Print(fmt.Sprintf("  + %s = %s", c.Key, env.MaskValue(c.NewValue)))
+		case env.ChangeChanged:
+			printer.Print(fmt.Sprintf("  ~ %s: %s -> %s", c.Key, env.MaskValue(c.OldValue), env.MaskValue(c.NewValue)))
+		}
+	}
+
+	response := prompt.PromptYesNo(cmd, fmt.Sprintf("Push %d change(s) to environment '%s'?", len(changes), t.envName), false)
+	if !response.Confirmed {
+		printer.Info("Promotion cancelled")
+		return nil
+	}
+
+	version, err := service.publish(t, newContent, remote)
+	if err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Promoted %d variable(s) from '%s' to '%s' (version %d)", len(changes), sourceName, t.envName, version))
+	return nil
+}
+
+// promotedContent copies the selected variables of source into content,
+// keeping everything else in content. With no keys every source variable is
+// selected. It returns the new content and the keys that were copied.
+func promotedContent(content, source string, keys, exclude []string) (string, []string, error) {
+	defs, err := env.Parse(source)
+	if err != nil {
+		return "", nil, fmt.Errorf("source environment is not a valid .env file: %w", err)
+	}
+
+	if len(keys) == 0 {
+		for _, v := range env.Effective(defs) {
+			keys = append(keys, v.Key)
+		}
+	}
+
+	defined := make(map[string]bool, len(defs))
+	for _, v := range defs {
+		defined[v.Key] = true
+	}
+	var missing []string
+	for _, key := range keys {
+		if !defined[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return "", nil, fmt.Errorf("source environment doesn't define %s", strings.Join(missing, ", "))
+	}
+
+	var promoted []string
+	for _, key := range keys {
+		if slices.Contains(exclude, key) || slices.Contains(promoted, key) {
+			continue
+		}
+		content, _, err = env.CopyVariable(content, source, key)
+		if err != nil {
+			return "", nil, fmt.Errorf("target environment is not a valid .env file: %w", err)
+		}
+		promoted = append(promoted, key)
+	}
+
+	if len(promoted) == 0 {
+		return "", nil, fmt.Errorf("no variables left to promote after --exclude")
+	}
+	return content, promoted, nil
+}
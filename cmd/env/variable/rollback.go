@@ -0,0 +1,177 @@
+package variable
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/Hyphen/cli/pkg/prompt"
+	"github.com/spf13/cobra"
+)
+
+var rollbackTo int
+
+// rollbackNotePrefix starts the comment recording a rollback in the pushed
+// content, which keeps the note in the environment's version history.
+const rollbackNotePrefix = "# Rolled back to version "
+
+var RollbackCmd = &cobra.Command{
+	Use:   "rollback ENVIRONMENT --to VERSION",
+	Short: "Restore a previous version of a remote environment",
+	Long: `
+The rollback command restores an earlier version of a remote environment by
+pushing its content as a new version, so the history is kept intact.
+
+The old version is decrypted and re-encrypted with the current secret key, so
+versions pushed before a key rotation can be restored as long as that key is
+still available. A comment recording the rollback is added to the top of the
+restored content, below the notes of earlier rollbacks. It is part of the
+environment, so it shows up when the environment is pulled.
+
+Before anything is pushed you see which keys will be added, changed or removed
+and are asked to confirm. Use list-versions to find the version to restore.
+
+Examples:
+  hyphen env rollback production --to 12
+  hyphen env rollback default --to 3 --yes
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunRollback(cmd, args[0])
+	},
+}
+
+func init() {
+	RollbackCmd.Flags().IntVar(&rollbackTo, "to", 0, "Version to restore")
+	addSkipValidationFlag(RollbackCmd)
+}
+
+func RunRollback(cmd *cobra.Command, environment string) error {
+	if rollbackTo <= 0 {
+		return fmt.Errorf("--to is required and must be a version number")
+	}
+
+	envName, err := env.GetEnvName(environment)
+	if err != nil {
+		return err
+	}
+
+	t, err := loadTarget()
+	if err != nil {
+		return err
+	}
+	t.envName = envName
+
+	db, err := database.Restore()
+	if err != nil {
+		return err
+	}
+	service := newService(env.NewService(), db)
+	if err := service.useSchema(); err != nil {
+		return err
+	}
+
+	content, remote, err := service.fetch(t)
+	if err != nil {
+		return err
+	}
+	if remote == nil || remote.Version == nil {
+		return fmt.Errorf("nothing has been pushed to the %s environment yet", t.envName)
+	}
+	latest := *remote.Version
+	if rollbackTo == latest {
+		printer.Info(fmt.Sprintf("Environment '%s' is already at version %d", t.envName, latest))
+		return nil
+	}
+	if rollbackTo > latest {
+		return fmt.Errorf("environment '%s' has no version %d, the latest is %d", t.envName, rollbackTo, latest)
+	}
+
+	restored, err := service.fetchVersion(t, rollbackTo)
+	if err != nil {
+		return err
+	}
+
+	current, err := env.Variables(content)
+	if err != nil {
+		return fmt.Errorf("remote environment '%s' is not a valid .env file: %w", t.envName, err)
+	}
+	restoredVars, err := env.Variables(restored)
+	if err != nil {
+		return fmt.Errorf("version %d of environment '%s' is not a valid .env file: %w", rollbackTo, t.envName, err)
+	}
+
+	changes := env.DiffVariables(current, restoredVars)
+	if len(changes) == 0 {
+		printer.Info(fmt.Sprintf("Version %d has the same variables as the latest version %d; pushing it records the rollback", rollbackTo, latest))
+	} else {
+		printChangePreview(t.envName, changes)
+	}
+
+	response := prompt.PromptYesNo(cmd, fmt.Sprintf("Roll back environment '%s' from version %d to version %d?", t.envName, latest, rollbackTo), true)
+	if !response.Confirmed {
+		printer.Info("Rollback cancelled")
+		return nil
+	}
+
+	version, err := service.publish(t, withRollbackNote(restored, rollbackTo, latest, time.Now()), remote)
+	if err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Rolled back environment '%s' to version %d (published as version %d)", t.envName, rollbackTo, version))
+	return nil
+}
+
+// fetchVersion returns the decrypted content of a specific remote version.
+func (s *service) fetchVersion(t target, version int) (string, error) {
+	e, err := s.envService.GetEnvironmentEnv(t.orgId, t.appId, t.envName, nil, &version)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return "", fmt.Errorf("environment '%s' has no version %d", t.envName, version)
+		}
+		return "", err
+	}
+
+	keyId := t.secret.SecretKeyId
+	if e.SecretKeyID != nil {
+		keyId = *e.SecretKeyID
+	} else if id, ok := models.EncryptedSecretKeyID(e.Data); ok {
+		keyId = id
+	}
+
+	content, err := e.DecryptData(t.secret)
+	if err != nil {
+		if keyId != t.secret.SecretKeyId {
+			return "", fmt.Errorf("version %d of environment '%s' was encrypted with secret key %d, which is not in the local keyring (the current key is %d): %w", version, t.envName, keyId, t.secret.SecretKeyId, err)
+		}
+		return "", err
+	}
+
+	if keyId != t.secret.SecretKeyId {
+		printer.Info(fmt.Sprintf("Version %d was encrypted with an older secret key and will be re-encrypted with the current one", version))
+	}
+	return content, nil
+}
+
+// withRollbackNote adds a comment recording the rollback to content, after
+// the notes of earlier rollbacks so they read in order. The API keeps no
+// metadata for a version, so the note is part of the content: it is pulled
+// with the file and shown by get and export like any other comment.
+func withRollbackNote(content string, to, from int, at time.Time) string {
+	lines := strings.Split(content, "\n")
+	notes := 0
+	for notes < len(lines) && strings.HasPrefix(lines[notes], rollbackNotePrefix) {
+		notes++
+	}
+
+	note := fmt.Sprintf("%s%d from version %d by hx env rollback on %s", rollbackNotePrefix, to, from, at.UTC().Format(time.RFC3339))
+	return strings.Join(append(lines[:notes:notes], append([]string{note}, lines[notes:]...)...), "\n")
+}
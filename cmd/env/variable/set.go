@@ -0,0 +1,91 @@
+package variable
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+var SetCmd = &cobra.Command{
+	Use:   "set KEY=VALUE [KEY=VALUE...]",
+	Short: "Set variables in a remote environment",
+	Long: `
+The set command changes individual variables in a remote environment without
+pushing your whole local .env file.
+
+It fetches and decrypts the latest version of the environment, updates only the
+given keys (keeping comments and ordering), and pushes the result as a new version.
+If your local .env file is unchanged since your last pull it is updated too.
+
+Examples:
+  hyphen env set API_URL=https://api.example.com -e production
+  hyphen env set FEATURE_X=on FEATURE_Y=off -e staging
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunSet(args)
+	},
+}
+
+func init() {
+	addSkipValidationFlag(SetCmd)
+}
+
+func RunSet(assignments []string) error {
+	type assignment struct{ key, value string }
+	var parsed []assignment
+	for _, a := range assignments {
+		key, value, ok := strings.Cut(a, "=")
+		if !ok {
+			return fmt.Errorf("invalid assignment %q, expected KEY=VALUE", a)
+		}
+		if err := env.ValidateKey(key); err != nil {
+			return err
+		}
+		parsed = append(parsed, assignment{key, value})
+	}
+
+	t, err := loadTarget()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Restore()
+	if err != nil {
+		return err
+	}
+	service := newService(env.NewService(), db)
+	if err := service.useSchema(); err != nil {
+		return err
+	}
+
+	content, remote, err := service.fetch(t)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range parsed {
+		content, err = env.SetVariable(content, a.key, a.value)
+		if err != nil {
+			return fmt.Errorf("remote environment '%s' is not a valid .env file: %w", t.envName, err)
+		}
+	}
+
+	version, err := service.publish(t, content, remote)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, len(parsed))
+	for i, a := range parsed {
+		keys[i] = a.key
+	}
+	printer.Success(fmt.Sprintf("Set %s in environment '%s' (version %d)", strings.Join(keys, ", "), t.envName, version))
+	return nil
+}
@@ -0,0 +1,85 @@
+package variable
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+var UnsetCmd = &cobra.Command{
+	Use:   "unset KEY [KEY...]",
+	Short: "Remove variables from a remote environment",
+	Long: `
+The unset command removes individual variables from a remote environment without
+pushing your whole local .env file.
+
+It fetches and decrypts the latest version of the environment, removes the given
+keys, and pushes the result as a new version.
+
+Examples:
+  hyphen env unset LEGACY_TOKEN -e production
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printer = cprint.NewCPrinter(flags.VerboseFlag)
+		return RunUnset(args)
+	},
+}
+
+func init() {
+	addSkipValidationFlag(UnsetCmd)
+}
+
+func RunUnset(keys []string) error {
+	for _, key := range keys {
+		if err := env.ValidateKey(key); err != nil {
+			return err
+		}
+	}
+
+	t, err := loadTarget()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Restore()
+	if err != nil {
+		return err
+	}
+	service := newService(env.NewService(), db)
+	if err := service.useSchema(); err != nil {
+		return err
+	}
+
+	content, remote, err := service.fetch(t)
+	if err != nil {
+		return err
+	}
+	if remote == nil {
+		return fmt.Errorf("environment '%s' has no variables yet", t.envName)
+	}
+
+	for _, key := range keys {
+		var removed bool
+		content, removed, err = env.UnsetVariable(content, key)
+		if err != nil {
+			return fmt.Errorf("remote environment '%s' is not a valid .env file: %w", t.envName, err)
+		}
+		if !removed {
+			return fmt.Errorf("variable %s is not set in environment '%s'", key, t.envName)
+		}
+	}
+
+	version, err := service.publish(t, content, remote)
+	if err != nil {
+		return err
+	}
+
+	printer.Success(fmt.Sprintf("Removed %s from environment '%s' (version %d)", strings.Join(keys, ", "), t.envName, version))
+	return nil
+}
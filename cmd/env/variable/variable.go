@@ -0,0 +1,208 @@
+package variable
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/internal/secret"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/spf13/cobra"
+)
+
+var printer *cprint.CPrinter
+
+// skipValidation is --skip-validation of the commands that push a new version.
+var skipValidation bool
+
+func addSkipValidationFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Push the new version even if it violates .env.schema")
+}
+
+// target identifies the remote environment a command in this package edits.
+type target struct {
+	orgId     string
+	projectId string
+	appId     string
+	envName   string
+	secret    models.Secret
+}
+
+func loadTarget() (target, error) {
+	orgId, err := flags.GetOrganizationID()
+	if err != nil {
+		return target{}, err
+	}
+
+	projectId, err := flags.GetProjectID()
+	if err != nil {
+		return target{}, err
+	}
+
+	appId, err := flags.GetApplicationID()
+	if err != nil {
+		return target{}, err
+	}
+
+	envName, err := env.GetEnvironmentID()
+	if err != nil {
+		return target{}, err
+	}
+
+	secretValue, _, err := secret.LoadSecret(orgId, projectId)
+	if err != nil {
+		return target{}, err
+	}
+
+	return target{
+		orgId:     orgId,
+		projectId: projectId,
+		appId:     appId,
+		envName:   envName,
+		secret:    secretValue,
+	}, nil
+}
+
+type service struct {
+	envService env.EnvServicer
+	db         database.Database
+	// schema, when set, is checked before each new version is pushed.
+	schema *env.Schema
+}
+
+func newService(envService env.EnvServicer, db database.Database) *service {
+	return &service{
+		envService: envService,
+		db:         db,
+	}
+}
+
+// useSchema makes publish check new versions against .env.schema, unless
+// --skip-validation is set.
+func (s *service) useSchema() error {
+	if skipValidation {
+		return nil
+	}
+	schema, err := env.LoadSchema(env.SchemaFileName)
+	if err != nil {
+		return err
+	}
+	s.schema = schema
+	return nil
+}
+
+// fetch returns the decrypted content of the latest remote version along with
+// the remote env it came from. A nil env means nothing has been pushed yet.
+func (s *service) fetch(t target) (string, *models.Env, error) {
+	remote, err := env.FetchRemote(s.envService, t.orgId, t.projectId, t.appId, t.envName, t.secret, nil)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			return "", nil, nil
+		}
+		return "", nil, err
+	}
+	return remote.Content, &remote.Env, nil
+}
+
+// publish encrypts content and pushes it as the next version of the remote
+// env, then brings the local file and database in line with it.
+func (s *service) publish(t target, content string, remote *models.Env) (int, error) {
+	if s.schema != nil {
+		vars, err := env.Variables(content)
+		if err != nil {
+			return 0, fmt.Errorf("the new version of environment '%s' is not a valid .env file: %w", t.envName, err)
+		}
+		if violations := s.schema.Validate(t.envName, vars); len(violations) > 0 {
+			return 0, fmt.Errorf("the new version of environment '%s' violates %s and was not pushed (use --skip-validation to push anyway):\n%s", t.envName, env.SchemaFileName, env.FormatViolations(violations))
+		}
+	}
+
+	e := env.NewFromContent(content)
+
+	newVersion := 1
+	replacingSecretKeyID := t.secret.SecretKeyId
+	if remote != nil {
+		if remote.Version != nil {
+			newVersion = *remote.Version + 1
+		}
+		if remote.SecretKeyID != nil {
+			replacingSecretKeyID = *remote.SecretKeyID
+		}
+	}
+	e.Version = &newVersion
+	e.SecretKeyID = &t.secret.SecretKeyId
+
+	encryptedData, err := e.EncryptData(t.secret)
+	if err != nil {
+		return 0, err
+	}
+	e.Data = encryptedData
+
+	if err := s.envService.PutEnvironmentEnv(t.orgId, t.appId, t.envName, replacingSecretKeyID, e); err != nil {
+		return 0, fmt.Errorf("failed to update cloud %s environment: %w", t.envName, err)
+	}
+
+	if err := s.syncLocal(t, content, newVersion); err != nil {
+		return newVersion, err
+	}
+
+	return newVersion, nil
+}
+
+// syncLocal records the pushed content in the local database so later pulls
+// don't flag drift. A local env file is rewritten only when it still matches
+// what was last pulled; local edits are never overwritten.
+func (s *service) syncLocal(t target, content string, version int) error {
+	fileName, err := env.GetFileName(t.envName)
+	if err != nil {
+		return err
+	}
+
+	key := database.SecretKey{
+		ProjectId: t.projectId,
+		AppId:     t.appId,
+		EnvName:   t.envName,
+	}
+
+	if _, err := os.Stat(fileName); err == nil {
+		local, err := env.New(fileName)
+		if err != nil {
+			return err
+		}
+
+		known, ok := s.db.GetSecret(key)
+		if !ok || known.Hash != local.HashData() {
+			printer.Warning(fmt.Sprintf("%s has local changes and was not updated. Run `hx env pull %s` to sync it.", fileName, t.envName))
+			return nil
+		}
+
+		if err := os.WriteFile(fileName, []byte(content), 0600); err != nil {
+			return errors.Wrapf(err, "Failed to update %s", fileName)
+		}
+	}
+
+	return s.db.UpsertSecret(key, content, version)
+}
+
+// printChangePreview lists the keys a change to envName adds, changes or
+// removes, without their values, and returns how many it removes.
+func printChangePreview(envName string, changes []env.VariableChange) int {
+	removals := 0
+	printer.PrintHeader(fmt.Sprintf("Changes to environment '%s'", envName))
+	for _, c := range changes {
+		switch c.Type {
+		case env.ChangeAdded:
+			printer.Print(fmt.Sprintf("  + %s", c.Key))
+		case env.ChangeChanged:
+			printer.Print(fmt.Sprintf("  ~ %s", c.Key))
+		case env.ChangeRemoved:
+			printer.Print(fmt.Sprintf("  - %s", c.Key))
+			removals++
+		}
+	}
+	return removals
+}
@@ -0,0 +1,233 @@
+package variable
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Hyphen/cli/internal/database"
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/internal/testutil"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func getTestTarget(secretKeyId int64) target {
+	base64SecretValue := base64.StdEncoding.EncodeToString([]byte("test-secret-test-secret-test-secret-test-secret"))
+	secret := models.NewSecret(base64SecretValue)
+	secret.SecretKeyId = secretKeyId
+	return target{
+		orgId:     "org-1",
+		projectId: "project-123",
+		appId:     "app-456",
+		envName:   "staging",
+		secret:    secret,
+	}
+}
+
+func TestPublish(t *testing.T) {
+	printer = cprint.NewCPrinter(false)
+
+	t.Run("pushes_next_version_with_remote_secret_key_id", func(t *testing.T) {
+		testutil.ChdirTemp(t)
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		svc := newService(mockEnvService, mockDB)
+
+		tgt := getTestTarget(111)
+		var remoteSecretKeyId int64 = 222
+		remoteVersion := 4
+		remote := &models.Env{Version: &remoteVersion, SecretKeyID: &remoteSecretKeyId}
+
+		var pushed models.Env
+		mockEnvService.On("PutEnvironmentEnv", "org-1", "app-456", "staging", remoteSecretKeyId, mock.Anything).
+			Run(func(args mock.Arguments) { pushed = args.Get(4).(models.Env) }).
+			Return(nil).Once()
+		mockDB.On("UpsertSecret", mock.Anything, "A=1\n", 5).Return(nil).Once()
+
+		version, err := svc.publish(tgt, "A=1\n", remote)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, version)
+		assert.Equal(t, 5, *pushed.Version)
+		assert.Equal(t, int64(111), *pushed.SecretKeyID)
+		decrypted, err := pushed.DecryptData(tgt.secret)
+		assert.NoError(t, err)
+		assert.Equal(t, "A=1\n", decrypted)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("updates_unmodified_local_file", func(t *testing.T) {
+		testutil.ChdirTemp(t)
+		assert.NoError(t, os.WriteFile(".env.staging", []byte("A=0\n"), 0600))
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		svc := newService(mockEnvService, mockDB)
+
+		mockEnvService.On("PutEnvironmentEnv", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{Version: 1, Hash: models.HashData("A=0\n")}, true)
+		mockDB.On("UpsertSecret", mock.Anything, "A=1\n", 1).Return(nil).Once()
+
+		_, err := svc.publish(getTestTarget(111), "A=1\n", nil)
+
+		assert.NoError(t, err)
+		content, err := os.ReadFile(".env.staging")
+		assert.NoError(t, err)
+		assert.Equal(t, "A=1\n", string(content))
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("keeps_modified_local_file", func(t *testing.T) {
+		testutil.ChdirTemp(t)
+		assert.NoError(t, os.WriteFile(".env.staging", []byte("A=local-edit\n"), 0600))
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		svc := newService(mockEnvService, mockDB)
+
+		mockEnvService.On("PutEnvironmentEnv", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockDB.On("GetSecret", mock.Anything).Return(database.Secret{Version: 1, Hash: models.HashData("A=0\n")}, true)
+
+		_, err := svc.publish(getTestTarget(111), "A=1\n", nil)
+
+		assert.NoError(t, err)
+		content, err := os.ReadFile(".env.staging")
+		assert.NoError(t, err)
+		assert.Equal(t, "A=local-edit\n", string(content))
+		mockDB.AssertNotCalled(t, "UpsertSecret", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects_versions_that_violate_the_schema", func(t *testing.T) {
+		testutil.ChdirTemp(t)
+		assert.NoError(t, os.WriteFile(env.SchemaFileName, []byte("variables:\n  PORT:\n    type: int\n"), 0600))
+
+		mockEnvService := env.NewMockEnvService()
+		svc := newService(mockEnvService, new(database.MockDatabase))
+		assert.NoError(t, svc.useSchema())
+
+		_, err := svc.publish(getTestTarget(111), "PORT=eighty\n", nil)
+
+		assert.ErrorContains(t, err, "violates .env.schema and was not pushed")
+		assert.ErrorContains(t, err, "PORT: is not an integer")
+		mockEnvService.AssertNotCalled(t, "PutEnvironmentEnv", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("skip_validation_ignores_the_schema", func(t *testing.T) {
+		testutil.ChdirTemp(t)
+		assert.NoError(t, os.WriteFile(env.SchemaFileName, []byte("variables:\n  PORT:\n    type: int\n"), 0600))
+		skipValidation = true
+		t.Cleanup(func() { skipValidation = false })
+
+		mockEnvService := env.NewMockEnvService()
+		mockDB := new(database.MockDatabase)
+		svc := newService(mockEnvService, mockDB)
+		assert.NoError(t, svc.useSchema())
+
+		mockEnvService.On("PutEnvironmentEnv", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		mockDB.On("UpsertSecret", mock.Anything, "PORT=eighty\n", 1).Return(nil).Once()
+
+		_, err := svc.publish(getTestTarget(111), "PORT=eighty\n", nil)
+
+		assert.NoError(t, err)
+		mockEnvService.AssertExpectations(t)
+	})
+}
+
+func TestImportedContent(t *testing.T) {
+	imported := []env.Variable{{Key: "A", Value: "new"}, {Key: "C", Value: "x y"}}
+
+	t.Run("replaces_remote_content", func(t *testing.T) {
+		content, err := importedContent("# keep\nA=old\nB=2\n", imported, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "A=new\nC=\"x y\"\n", content)
+	})
+
+	t.Run("merges_into_remote_content", func(t *testing.T) {
+		content, err := importedContent("# keep\nA=old\nB=2\n", imported, true)
+		assert.NoError(t, err)
+		assert.Equal(t, "# keep\nA=new\nB=2\nC=\"x y\"\n", content)
+	})
+}
+
+func TestFetchVersion(t *testing.T) {
+	printer = cprint.NewCPrinter(false)
+	tgt := getTestTarget(111)
+	version := 3
+
+	t.Run("decrypts_the_requested_version", func(t *testing.T) {
+		e := env.NewFromContent("A=1\n")
+		data, err := e.EncryptData(tgt.secret)
+		assert.NoError(t, err)
+		e.Data = data
+
+		mockEnvService := env.NewMockEnvService()
+		mockEnvService.On("GetEnvironmentEnv", "org-1", "app-456", "staging", (*int64)(nil), &version).Return(e, nil)
+
+		content, err := newService(mockEnvService, nil).fetchVersion(tgt, version)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "A=1\n", content)
+	})
+
+	t.Run("explains_versions_encrypted_with_another_key", func(t *testing.T) {
+		old := getTestTarget(99)
+		old.secret.Base64SecretKey = "b2xkLWtleS1vbGQta2V5LW9sZC1rZXktb2xkLWtleQ=="
+		e := env.NewFromContent("A=1\n")
+		data, err := e.EncryptData(old.secret)
+		assert.NoError(t, err)
+		e.Data = data
+
+		mockEnvService := env.NewMockEnvService()
+		mockEnvService.On("GetEnvironmentEnv", "org-1", "app-456", "staging", (*int64)(nil), &version).Return(e, nil)
+
+		_, err = newService(mockEnvService, nil).fetchVersion(tgt, version)
+
+		assert.ErrorContains(t, err, "was encrypted with secret key 99, which is not in the local keyring (the current key is 111)")
+	})
+}
+
+func TestWithRollbackNote(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, "# Rolled back to version 3 from version 5 by hx env rollback on 2026-01-02T03:04:05Z\n# app\nA=1\n", withRollbackNote("# app\nA=1\n", 3, 5, at))
+
+	content := withRollbackNote("# Rolled back to version 1 from version 2 by hx env rollback on 2025-01-01T00:00:00Z\nA=1\n", 3, 5, at)
+	assert.Equal(t, "# Rolled back to version 1 from version 2 by hx env rollback on 2025-01-01T00:00:00Z\n# Rolled back to version 3 from version 5 by hx env rollback on 2026-01-02T03:04:05Z\nA=1\n", content)
+}
+
+func TestPromotedContent(t *testing.T) {
+	source := "API_URL=https://staging\nFEATURE_X=on\nDATABASE_URL=postgres://staging\n"
+	target := "# production\nAPI_URL=https://old\nONLY_PROD=1\n"
+
+	t.Run("copies_selected_keys", func(t *testing.T) {
+		content, promoted, err := promotedContent(target, source, []string{"API_URL", "FEATURE_X"}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "# production\nAPI_URL=https://staging\nONLY_PROD=1\nFEATURE_X=on\n", content)
+		assert.Equal(t, []string{"API_URL", "FEATURE_X"}, promoted)
+	})
+
+	t.Run("copies_all_but_excluded_keys", func(t *testing.T) {
+		content, promoted, err := promotedContent(target, source, nil, []string{"DATABASE_URL"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "# production\nAPI_URL=https://staging\nONLY_PROD=1\nFEATURE_X=on\n", content)
+		assert.Equal(t, []string{"API_URL", "FEATURE_X"}, promoted)
+	})
+
+	t.Run("rejects_keys_missing_from_source", func(t *testing.T) {
+		_, _, err := promotedContent(target, source, []string{"API_URL", "NOPE"}, nil)
+
+		assert.ErrorContains(t, err, "source environment doesn't define NOPE")
+	})
+
+	t.Run("rejects_empty_selection", func(t *testing.T) {
+		_, _, err := promotedContent(target, source, []string{"API_URL"}, []string{"API_URL"})
+
+		assert.ErrorContains(t, err, "no variables left to promote")
+	})
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Hyphen/cli/cmd/app"
 	"github.com/Hyphen/cli/cmd/auth"
@@ -9,6 +11,7 @@ import (
 	"github.com/Hyphen/cli/cmd/build"
 	"github.com/Hyphen/cli/cmd/code"
 	configcmd "github.com/Hyphen/cli/cmd/config"
+	contextcmd "github.com/Hyphen/cli/cmd/context"
 	"github.com/Hyphen/cli/cmd/deploy"
 	"github.com/Hyphen/cli/cmd/entrypoint"
 	"github.com/Hyphen/cli/cmd/env"
@@ -22,7 +25,10 @@ import (
 	"github.com/Hyphen/cli/cmd/setproject"
 	"github.com/Hyphen/cli/cmd/update"
 	"github.com/Hyphen/cli/cmd/version"
+	"github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/pkg/apiconf"
 	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
 	"github.com/Hyphen/cli/pkg/flags"
 	"github.com/Hyphen/cli/pkg/toggle"
 	"github.com/spf13/cobra"
@@ -39,6 +45,24 @@ The Hyphen CLI is a command-line interface for managing your Hyphen projects, en
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		config.SetContextOverride(flags.ContextFlag)
+		prepareGlobalDirectory()
+		// The context commands must keep working when the selected context
+		// is missing, so it can be created or another one picked.
+		if topLevelCommand(cmd).Name() != "context" {
+			if err := apiconf.LoadEndpoints(); err != nil {
+				return err
+			}
+		}
+		if findsLocalRoot(cmd) {
+			root, err := config.EnterLocalRoot()
+			if err != nil {
+				return err
+			}
+			if root != "" && root != config.InvocationDirectory() {
+				cprint.NewCPrinter(flags.VerboseFlag).PrintVerbose(fmt.Sprintf("Using the app at %s", root))
+			}
+		}
 		update.RunAutoUpdate(cmd)
 		return autoinit.Ensure(cmd, args)
 	},
@@ -50,6 +74,7 @@ func init() {
 	rootCmd.AddCommand(version.VersionCmd)
 	rootCmd.AddCommand(update.UpdateCmd)
 	rootCmd.AddCommand(configcmd.ConfigCmd)
+	rootCmd.AddCommand(contextcmd.ContextCmd)
 	rootCmd.AddCommand(initialize.InitCmd)
 	rootCmd.AddCommand(auth.AuthCmd)
 	rootCmd.AddCommand(setorg.SetOrgCmd)
@@ -74,6 +99,7 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVarP(&flags.OrganizationFlag, "organization", "o", "", "Organization ID (e.g., org_123)")
 	rootCmd.PersistentFlags().StringVarP(&flags.ProjectFlag, "project", "p", "", "Project ID (e.g., proj_123)")
+	rootCmd.PersistentFlags().StringVar(&flags.ContextFlag, "context", "", "Configuration context to use (overrides HX_CONTEXT)")
 
 	rootCmd.PersistentFlags().BoolVarP(&flags.YesFlag, "yes", "y", false, "Automatically answer yes for prompts")
 	rootCmd.PersistentFlags().BoolVarP(&flags.NoFlag, "no", "n", false, "Automatically answer no for prompts")
@@ -84,6 +110,41 @@ func init() {
 	rootCmd.PersistentFlags().MarkHidden("dev")
 }
 
+// findsLocalRoot reports whether a command runs from the closest directory
+// above the current one that has a .hx. Only the commands that work on a
+// project's .hx, .hxkey and env files do; the others, such as init, build and
+// code, keep working in the current directory.
+func findsLocalRoot(cmd *cobra.Command) bool {
+	switch topLevelCommand(cmd).Name() {
+	case "env", "pull", "push", "config":
+		return true
+	}
+	return false
+}
+
+// topLevelCommand returns the command under the root that cmd belongs to.
+func topLevelCommand(cmd *cobra.Command) *cobra.Command {
+	top := cmd
+	for top.Parent() != nil && top.Parent().Parent() != nil {
+		top = top.Parent()
+	}
+	return top
+}
+
+// prepareGlobalDirectory creates the global directory and reports the files
+// of older CLIs copied into it. Messages go to stderr so they never end up
+// in the output of commands such as `hx env get`. A failure isn't fatal:
+// commands that need the directory report it themselves.
+func prepareGlobalDirectory() {
+	dir, copied, err := config.PrepareGlobalDirectory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+	}
+	if len(copied) > 0 {
+		fmt.Fprintf(os.Stderr, "Copied %s to %s, where hx now keeps its configuration. Older versions of hx keep using the originals; delete them once you no longer need them.\n", strings.Join(copied, ", "), dir)
+	}
+}
+
 func Execute() {
 	canUseAgent := toggle.GetBooleanValue("canUseAgent", false)
 	if canUseAgent {
@@ -95,6 +156,10 @@ func Execute() {
 		rootCmd.AddCommand(build.BuildCmd)
 	}
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *errors.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		cprint.Error(rootCmd, err, flags.VerboseFlag)
 		os.Exit(1)
 	}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindsLocalRoot(t *testing.T) {
+	testCases := []struct {
+		args []string
+		want bool
+	}{
+		{args: []string{"env", "run"}, want: true},
+		{args: []string{"pull"}, want: true},
+		{args: []string{"config", "get"}, want: true},
+		{args: []string{"init"}, want: false},
+		{args: []string{"link"}, want: false},
+		{args: []string{"version"}, want: false},
+	}
+
+	for _, tc := range testCases {
+		cmd, _, err := rootCmd.Find(tc.args)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, findsLocalRoot(cmd), tc.args)
+	}
+}
+
+func TestRunFromSubdirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.ConfigDirEnv, filepath.Join(home, "hx"))
+	t.Setenv(config.ContextEnv, "")
+
+	app, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(app, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(app, config.ManifestConfigFile), []byte(`{"app_id": "app_1"}`), 0o644))
+	src := filepath.Join(app, "src")
+	require.NoError(t, os.Mkdir(src, 0o755))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(src))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	rootCmd.SetArgs([]string{"config", "set", "app_name", "web", "--local"})
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	require.NoError(t, rootCmd.Execute())
+
+	_, err = os.Stat(filepath.Join(src, config.ManifestConfigFile))
+	assert.True(t, os.IsNotExist(err), "expected no .hx in the subdirectory")
+
+	data, err := os.ReadFile(filepath.Join(app, config.ManifestConfigFile))
+	require.NoError(t, err)
+	var cfg config.Config
+	require.NoError(t, json.Unmarshal(data, &cfg))
+	require.NotNil(t, cfg.AppName)
+	assert.Equal(t, "web", *cfg.AppName)
+	assert.Equal(t, src, config.ResolveInvocationPath("."))
+}
+
+func TestUnknownContextFailsBeforeRunning(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(config.ConfigDirEnv, filepath.Join(home, "hx"))
+	t.Setenv(config.ContextEnv, "")
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		flags.ContextFlag = ""
+		config.SetContextOverride("")
+	})
+
+	rootCmd.SetArgs([]string{"version", "--context", "missing"})
+	assert.ErrorContains(t, rootCmd.Execute(), "context 'missing' does not exist")
+
+	rootCmd.SetArgs([]string{"context", "list", "--context", "missing"})
+	assert.NoError(t, rootCmd.Execute())
+}
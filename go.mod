@@ -13,7 +13,10 @@ require (
 	github.com/zishang520/socket.io/clients/socket/v3 v3.0.0-rc.9
 	github.com/zishang520/socket.io/v3 v3.0.0-rc.9
 	go.uber.org/thriftrw v1.32.0
+	golang.org/x/crypto v0.45.0
+	golang.org/x/sys v0.38.0
 	golang.org/x/term v0.37.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -33,7 +36,6 @@ require (
 	github.com/zishang520/socket.io/servers/engine/v3 v3.0.0-rc.9 // indirect
 	github.com/zishang520/socket.io/servers/socket/v3 v3.0.0-rc.9 // indirect
 	github.com/zishang520/webtransport-go v0.9.1 // indirect
-	golang.org/x/crypto v0.45.0 // indirect
 	golang.org/x/net v0.47.0 // indirect
 	resty.dev/v3 v3.0.0-beta.4 // indirect
 )
@@ -61,11 +63,9 @@ require (
 	github.com/patrickmn/go-cache v2.1.0+incompatible // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
-	github.com/spf13/pflag v1.0.6 // indirect
+	github.com/spf13/pflag v1.0.6
 	github.com/stretchr/objx v0.5.2 // indirect
 	golang.org/x/exp v0.0.0-20250506013437-ce4c2cf36ca6 // indirect
 	golang.org/x/sync v0.18.0 // indirect
-	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
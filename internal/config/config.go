@@ -2,7 +2,6 @@ package config
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"path/filepath"
 
@@ -35,7 +34,10 @@ type Config struct {
 	IsMonorepo         *bool          `json:"is_monorepo,omitempty"`
 	Project            *ConfigProject `json:"project,omitempty"`
 	AutoUpdateDisabled *bool          `json:"auto_update_disabled,omitempty"`
-	Database           interface{}    `json:"database,omitempty"`
+	Endpoints          *Endpoints     `json:"endpoints,omitempty"`
+	// Database is where older CLIs kept the pull/push database. It is moved
+	// to the state store the first time the database is restored.
+	Database interface{} `json:"database,omitempty"`
 }
 
 func (c *Config) IsMonorepoProject() bool {
@@ -61,24 +63,22 @@ func (w *ConfigProject) AddApp(appDir string) {
 }
 
 func GlobalInitializeConfig(mc Config) error {
-	configDirectory := GetGlobalDirectory()
-
-	manifestConfigFilePath := fmt.Sprintf("%s/%s", configDirectory, ManifestConfigFile)
+	manifestConfigFilePath, err := globalConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := FS.MkdirAll(filepath.Dir(manifestConfigFilePath), 0o755); err != nil {
+		return errors.Wrap(err, "Failed to create global directory")
+	}
 
 	return InitializeConfig(mc, manifestConfigFilePath)
 }
 
-func GetGlobalDirectory() string {
-	home, err := os.UserHomeDir()
+func UpsertGlobalConfig(mc Config) error {
+	globDir, err := GlobalConfigDirectory()
 	if err != nil {
-		fmt.Println("Error retrieving home directory:", err)
-		return ""
+		return err
 	}
-	return home
-}
-
-func UpsertGlobalConfig(mc Config) error {
-	globDir := GetGlobalDirectory()
 
 	if err := FS.MkdirAll(globDir, 0o755); err != nil {
 		return errors.Wrap(err, "Failed to create global directory")
@@ -92,6 +92,9 @@ func UpsertGlobalConfig(mc Config) error {
 		if mc.AutoUpdateDisabled == nil {
 			mc.AutoUpdateDisabled = existingConfig.AutoUpdateDisabled
 		}
+		if mc.Endpoints == nil {
+			mc.Endpoints = existingConfig.Endpoints
+		}
 	} else if !os.IsNotExist(err) {
 		return errors.Wrap(err, "Failed to read existing global config")
 	}
@@ -163,7 +166,11 @@ func RestoreConfigFromFile(manifestConfigFile string) (Config, error) {
 	var mconfig Config
 	var hasConfig bool
 
-	globalConfigFile := filepath.Join(GetGlobalDirectory(), manifestConfigFile)
+	globalDir, err := GlobalConfigDirectory()
+	if err != nil {
+		return Config{}, err
+	}
+	globalConfigFile := filepath.Join(globalDir, manifestConfigFile)
 
 	globalConfig, err := readAndUnmarshalConfigJSON[Config](globalConfigFile)
 	if err == nil {
@@ -192,7 +199,10 @@ func RestoreConfigFromFile(manifestConfigFile string) (Config, error) {
 }
 
 func RestoreGlobalConfig() (Config, error) {
-	globalConfigFile := fmt.Sprintf("%s/%s", GetGlobalDirectory(), ManifestConfigFile)
+	globalConfigFile, err := globalConfigPath()
+	if err != nil {
+		return Config{}, err
+	}
 	return readAndUnmarshalConfigJSON[Config](globalConfigFile)
 }
 
@@ -244,7 +254,10 @@ func UpsertOrganizationID(organizationID string) error {
 	var mconfig Config
 	var hasConfig bool
 
-	globalConfigFile := fmt.Sprintf("%s/%s", GetGlobalDirectory(), ManifestConfigFile)
+	globalConfigFile, err := globalConfigPath()
+	if err != nil {
+		return err
+	}
 	localConfigFile := ManifestConfigFile
 	localConfig, localConfigErr := readAndUnmarshalConfigJSON[Config](localConfigFile)
 	if localConfigErr == nil {
@@ -266,6 +279,9 @@ func UpsertOrganizationID(organizationID string) error {
 			AppAlternateId: nil,
 			OrganizationId: organizationID,
 		}
+		if err := FS.MkdirAll(filepath.Dir(globalConfigFile), 0o755); err != nil {
+			return errors.Wrap(err, "Failed to create global directory")
+		}
 		jsonData, err := json.MarshalIndent(mc, "", "  ")
 		if err != nil {
 			return errors.Wrap(err, "Error encoding JSON")
@@ -297,7 +313,10 @@ func UpsertOrganizationID(organizationID string) error {
 }
 
 func UpsertGlobalOrganizationID(organizationID string) error {
-	globalDir := GetGlobalDirectory()
+	globalDir, err := GlobalConfigDirectory()
+	if err != nil {
+		return err
+	}
 	globalConfigFile := filepath.Join(globalDir, ManifestConfigFile)
 
 	var mconfig Config
@@ -337,7 +356,10 @@ func UpsertGlobalOrganizationID(organizationID string) error {
 }
 
 func UpsertGlobalAutoUpdateEnabled(enabled bool) error {
-	globalDir := GetGlobalDirectory()
+	globalDir, err := GlobalConfigDirectory()
+	if err != nil {
+		return err
+	}
 	globalConfigFile := filepath.Join(globalDir, ManifestConfigFile)
 
 	var mconfig Config
@@ -375,7 +397,10 @@ func UpsertProject(project models.Project) error {
 	var mconfig Config
 	var hasConfig bool
 
-	globalConfigFile := fmt.Sprintf("%s/%s", GetGlobalDirectory(), ManifestConfigFile)
+	globalConfigFile, err := globalConfigPath()
+	if err != nil {
+		return err
+	}
 	localConfigFile := ManifestConfigFile
 
 	localConfig, localConfigErr := readAndUnmarshalConfigJSON[Config](localConfigFile)
@@ -402,6 +427,9 @@ func UpsertProject(project models.Project) error {
 			AppAlternateId:     nil,
 			OrganizationId:     "",
 		}
+		if err := FS.MkdirAll(filepath.Dir(globalConfigFile), 0o755); err != nil {
+			return errors.Wrap(err, "Failed to create global directory")
+		}
 		jsonData, err := json.MarshalIndent(mc, "", "  ")
 		if err != nil {
 			return errors.Wrap(err, "Error encoding JSON")
@@ -435,7 +463,10 @@ func UpsertProject(project models.Project) error {
 }
 
 func UpsertGlobalProject(project models.Project) error {
-	globalDir := GetGlobalDirectory()
+	globalDir, err := GlobalConfigDirectory()
+	if err != nil {
+		return err
+	}
 	globalConfigFile := filepath.Join(globalDir, ManifestConfigFile)
 
 	var mconfig Config
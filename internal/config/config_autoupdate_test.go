@@ -32,7 +32,7 @@ func TestUpsertGlobalAutoUpdateEnabled(t *testing.T) {
 		t.Fatalf("unexpected error writing auto-update setting: %v", err)
 	}
 
-	cfg := mustReadGlobalConfig(t, tempHome)
+	cfg := mustReadGlobalConfig(t)
 	if cfg.AutoUpdateDisabled == nil || !*cfg.AutoUpdateDisabled {
 		t.Fatalf("expected auto_update_disabled to be true after disabling auto-update")
 	}
@@ -50,7 +50,7 @@ func TestUpsertGlobalConfigPreservesAutoUpdateSetting(t *testing.T) {
 		t.Fatalf("unexpected error writing global config: %v", err)
 	}
 
-	cfg := mustReadGlobalConfig(t, tempHome)
+	cfg := mustReadGlobalConfig(t)
 	if cfg.AutoUpdateDisabled == nil || !*cfg.AutoUpdateDisabled {
 		t.Fatalf("expected auto_update_disabled to remain true after other global config updates")
 	}
@@ -59,11 +59,14 @@ func TestUpsertGlobalConfigPreservesAutoUpdateSetting(t *testing.T) {
 	}
 }
 
-func mustReadGlobalConfig(t *testing.T, homeDir string) Config {
+func mustReadGlobalConfig(t *testing.T) Config {
 	t.Helper()
 
-	configPath := filepath.Join(homeDir, ManifestConfigFile)
-	data, err := os.ReadFile(configPath)
+	dir, err := GetGlobalDirectory()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ManifestConfigFile))
 	if err != nil {
 		t.Fatalf("failed to read global config: %v", err)
 	}
@@ -81,4 +84,7 @@ func setTestHome(t *testing.T, homeDir string) {
 
 	t.Setenv("HOME", homeDir)
 	t.Setenv("USERPROFILE", homeDir)
+	t.Setenv(ContextEnv, "")
+	t.Setenv(ConfigDirEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
 }
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Hyphen/cli/pkg/errors"
+)
+
+// DefaultContext is the context whose configuration is the global .hx file
+// in the global directory, as it was before contexts existed.
+const DefaultContext = "default"
+
+// ContextEnv names the environment variable that selects a context for a
+// single invocation.
+const ContextEnv = "HX_CONTEXT"
+
+// ContextsDirectory is where named contexts are kept, in the global
+// directory. Each context is a directory holding its own .hx file; the
+// current file records the context in use.
+var ContextsDirectory = ".hxcontexts"
+
+var contextNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// contextOverride is the context given with --context.
+var contextOverride string
+
+// Endpoints overrides the API base URLs of a context, for instance to point
+// it at a development deployment.
+type Endpoints struct {
+	Api     string `json:"api,omitempty"`
+	Horizon string `json:"horizon,omitempty"`
+	App     string `json:"app,omitempty"`
+	Auth    string `json:"auth,omitempty"`
+	Vinz    string `json:"vinz,omitempty"`
+}
+
+// SetContextOverride selects the context for this invocation, ahead of
+// HX_CONTEXT and the current context.
+func SetContextOverride(name string) {
+	contextOverride = name
+}
+
+// ActiveContext returns the context in use: the one given with --context,
+// HX_CONTEXT, or the current context, in that order.
+func ActiveContext() string {
+	if contextOverride != "" {
+		return contextOverride
+	}
+	if name := os.Getenv(ContextEnv); name != "" {
+		return name
+	}
+	if name, err := CurrentContext(); err == nil && name != "" {
+		return name
+	}
+	return DefaultContext
+}
+
+// CurrentContext returns the context selected with `hx context use`.
+func CurrentContext() (string, error) {
+	path, err := currentContextFile()
+	if err != nil {
+		return "", err
+	}
+	data, err := FS.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultContext, nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read the current context")
+	}
+	name := strings.TrimSpace(string(data))
+	if name == "" {
+		return DefaultContext, nil
+	}
+	return name, nil
+}
+
+// UseContext makes name the current context.
+func UseContext(name string) error {
+	if !ContextExists(name) {
+		return fmt.Errorf("context '%s' does not exist", name)
+	}
+	path, err := currentContextFile()
+	if err != nil {
+		return err
+	}
+	if err := FS.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return errors.Wrap(err, "Failed to create contexts directory")
+	}
+	if err := FS.WriteFile(path, []byte(name+"\n"), 0o600); err != nil {
+		return errors.Wrap(err, "Failed to save the current context")
+	}
+	return nil
+}
+
+// ResolveActiveContext returns the active context. It fails if the context
+// was never created, so a typo in --context never falls back to other
+// credentials.
+func ResolveActiveContext() (string, error) {
+	name := ActiveContext()
+	if !ContextExists(name) {
+		return "", fmt.Errorf("context '%s' does not exist. Create it with `hx context create %s` or pick another with `hx context list`", name, name)
+	}
+	return name, nil
+}
+
+// GlobalConfigDirectory returns the directory holding the global .hx file,
+// global .hxkey and keyring of the active context.
+func GlobalConfigDirectory() (string, error) {
+	name, err := ResolveActiveContext()
+	if err != nil {
+		return "", err
+	}
+	return contextDirectory(name)
+}
+
+func globalConfigPath() (string, error) {
+	dir, err := GlobalConfigDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ManifestConfigFile), nil
+}
+
+// ListContexts returns the names of every context, starting with the default.
+func ListContexts() ([]string, error) {
+	dir, err := contextsDirectory()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "Failed to list contexts")
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && contextNamePattern.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultContext}, names...), nil
+}
+
+// ContextExists reports whether a context has been created.
+func ContextExists(name string) bool {
+	if name == DefaultContext {
+		return true
+	}
+	if !contextNamePattern.MatchString(name) {
+		return false
+	}
+	dir, err := contextDirectory(name)
+	if err != nil {
+		return false
+	}
+	info, err := FS.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// CreateContext creates a named context with the given configuration, which
+// typically holds just an organization, project and endpoints until someone
+// authenticates in it.
+func CreateContext(name string, mc Config) error {
+	if name == DefaultContext {
+		return fmt.Errorf("the %s context always exists", DefaultContext)
+	}
+	if !contextNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid context name '%s': use letters, digits, '.', '-' and '_'", name)
+	}
+	if ContextExists(name) {
+		return fmt.Errorf("context '%s' already exists", name)
+	}
+
+	dir, err := contextDirectory(name)
+	if err != nil {
+		return err
+	}
+	if err := FS.MkdirAll(dir, 0o700); err != nil {
+		return errors.Wrapf(err, "Failed to create context '%s'", name)
+	}
+	return InitializeConfig(mc, filepath.Join(dir, ManifestConfigFile))
+}
+
+// DeleteContext deletes a named context and its credentials. If it was the
+// current context, the default context becomes current.
+func DeleteContext(name string) error {
+	if name == DefaultContext {
+		return fmt.Errorf("the %s context can't be deleted", DefaultContext)
+	}
+	if !ContextExists(name) {
+		return fmt.Errorf("context '%s' does not exist", name)
+	}
+
+	if current, err := CurrentContext(); err == nil && current == name {
+		path, err := currentContextFile()
+		if err != nil {
+			return err
+		}
+		if err := FS.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Failed to reset the current context")
+		}
+	}
+	dir, err := contextDirectory(name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrapf(err, "Failed to delete context '%s'", name)
+	}
+	return nil
+}
+
+// RestoreContextConfig returns the global configuration of a context.
+func RestoreContextConfig(name string) (Config, error) {
+	dir, err := contextDirectory(name)
+	if err != nil {
+		return Config{}, err
+	}
+	return readAndUnmarshalConfigJSON[Config](filepath.Join(dir, ManifestConfigFile))
+}
+
+func contextsDirectory() (string, error) {
+	dir, err := GetGlobalDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ContextsDirectory), nil
+}
+
+func contextDirectory(name string) (string, error) {
+	if name == DefaultContext {
+		return GetGlobalDirectory()
+	}
+	dir, err := contextsDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+func currentContextFile() (string, error) {
+	dir, err := contextsDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "current"), nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContexts(t *testing.T) {
+	tempHome := t.TempDir()
+	setTestHome(t, tempHome)
+	t.Cleanup(func() { SetContextOverride("") })
+
+	if err := UpsertGlobalConfig(Config{OrganizationId: "org_personal"}); err != nil {
+		t.Fatalf("unexpected error writing global config: %v", err)
+	}
+	if err := CreateContext("client", Config{OrganizationId: "org_client", Endpoints: &Endpoints{Api: "https://api.client.test"}}); err != nil {
+		t.Fatalf("unexpected error creating context: %v", err)
+	}
+	if err := CreateContext("client", Config{}); err == nil {
+		t.Fatal("expected an error creating a context twice")
+	}
+	if err := CreateContext("../escape", Config{}); err == nil {
+		t.Fatal("expected an error for an invalid context name")
+	}
+
+	assertOrganization := func(t *testing.T, want string) {
+		t.Helper()
+		cfg, err := RestoreConfig()
+		if err != nil {
+			t.Fatalf("unexpected error restoring config: %v", err)
+		}
+		if cfg.OrganizationId != want {
+			t.Fatalf("expected organization %q, got %q", want, cfg.OrganizationId)
+		}
+	}
+
+	t.Run("default_context_is_the_global_file", func(t *testing.T) {
+		assertOrganization(t, "org_personal")
+	})
+
+	t.Run("use_switches_the_current_context", func(t *testing.T) {
+		if err := UseContext("client"); err != nil {
+			t.Fatalf("unexpected error switching context: %v", err)
+		}
+		assertOrganization(t, "org_client")
+
+		token := "token"
+		if err := UpsertGlobalConfig(Config{HyphenAccessToken: &token}); err != nil {
+			t.Fatalf("unexpected error writing global config: %v", err)
+		}
+		cfg, err := RestoreContextConfig("client")
+		if err != nil {
+			t.Fatalf("unexpected error reading context: %v", err)
+		}
+		if cfg.HyphenAccessToken == nil || cfg.Endpoints == nil || cfg.Endpoints.Api != "https://api.client.test" {
+			t.Fatalf("expected credentials saved in the context with its endpoints kept, got %+v", cfg)
+		}
+		if personal := mustReadGlobalConfig(t); personal.HyphenAccessToken != nil {
+			t.Fatal("expected the default context to be left alone")
+		}
+	})
+
+	t.Run("env_and_flag_override_the_current_context", func(t *testing.T) {
+		t.Setenv(ContextEnv, DefaultContext)
+		assertOrganization(t, "org_personal")
+
+		SetContextOverride("client")
+		defer SetContextOverride("")
+		cfg, err := RestoreConfig()
+		if err != nil {
+			t.Fatalf("unexpected error restoring config: %v", err)
+		}
+		if cfg.HyphenAccessToken == nil {
+			t.Fatal("expected the client context's credentials")
+		}
+	})
+
+	t.Run("unknown_context_is_an_error", func(t *testing.T) {
+		t.Setenv(ContextEnv, "missing")
+		if _, err := RestoreConfig(); err == nil {
+			t.Fatal("expected an error for a context that doesn't exist")
+		}
+	})
+
+	t.Run("delete_resets_the_current_context", func(t *testing.T) {
+		if err := DeleteContext("client"); err != nil {
+			t.Fatalf("unexpected error deleting context: %v", err)
+		}
+		dir, err := contextsDirectory()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "client")); !os.IsNotExist(err) {
+			t.Fatal("expected the context directory to be removed")
+		}
+		if ActiveContext() != DefaultContext {
+			t.Fatalf("expected the default context to become active, got %q", ActiveContext())
+		}
+		if err := DeleteContext(DefaultContext); err == nil {
+			t.Fatal("expected an error deleting the default context")
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/Hyphen/cli/pkg/errors"
+)
+
+// ConfigDirEnv names the environment variable that overrides the global
+// directory, for instance to give each CI job its own credentials.
+const ConfigDirEnv = "HX_CONFIG_DIR"
+
+// legacyGlobalFiles are the files released CLIs kept directly in the home
+// directory. Contexts and the keyring never lived there.
+var legacyGlobalFiles = []string{ManifestConfigFile, ManifestSecretFile}
+
+// GetGlobalDirectory returns the directory holding the global .hx, .hxkey,
+// contexts and keyring: $HX_CONFIG_DIR, $XDG_CONFIG_HOME/hx, or
+// ~/.config/hx (%AppData%\hx on Windows). It doesn't create the directory;
+// see PrepareGlobalDirectory.
+func GetGlobalDirectory() (string, error) {
+	if dir := os.Getenv(ConfigDirEnv); dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to resolve %s", ConfigDirEnv)
+		}
+		return abs, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to find your home directory")
+	}
+	return filepath.Join(configHome(home), "hx"), nil
+}
+
+func configHome(home string) string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			return dir
+		}
+	}
+	return filepath.Join(home, ".config")
+}
+
+// PrepareGlobalDirectory creates the global directory if it doesn't exist
+// yet. When it does, the files older CLIs kept in the home directory are
+// copied into it and their paths returned; the originals are left in place
+// so those CLIs keep working. Nothing is copied into a directory set with
+// HX_CONFIG_DIR, so an isolated directory never picks up the user's
+// credentials.
+func PrepareGlobalDirectory() (string, []string, error) {
+	dir, err := GetGlobalDirectory()
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return dir, nil, errors.Wrapf(err, "Failed to create %s", dir)
+	}
+	if os.Getenv(ConfigDirEnv) != "" {
+		return dir, nil, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return dir, nil, nil
+	}
+	copied, err := copyLegacyGlobalFiles(home, dir)
+	return dir, copied, err
+}
+
+// copyLegacyGlobalFiles copies each legacy file from home into dir and
+// returns the paths it copied.
+func copyLegacyGlobalFiles(home, dir string) ([]string, error) {
+	var copied []string
+	for _, name := range legacyGlobalFiles {
+		legacy := filepath.Join(home, name)
+		info, err := os.Stat(legacy)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if err := copyFile(legacy, filepath.Join(dir, name), info.Mode().Perm()); err != nil {
+			return copied, errors.Wrapf(err, "Failed to copy %s to %s", legacy, dir)
+		}
+		copied = append(copied, legacy)
+	}
+	return copied, nil
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// homeDirectory returns the user's home directory, or "" if it is unknown.
+func homeDirectory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGetGlobalDirectory(t *testing.T) {
+	t.Run("has_no_side_effects", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("the config home is %AppData% on Windows")
+		}
+		tempHome := t.TempDir()
+		setTestHome(t, tempHome)
+
+		dir, err := GetGlobalDirectory()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := filepath.Join(tempHome, ".config", "hx"); dir != want {
+			t.Fatalf("expected %s, got %s", want, dir)
+		}
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Fatalf("expected %s not to be created", dir)
+		}
+	})
+
+	t.Run("follows_xdg_config_home", func(t *testing.T) {
+		tempHome := t.TempDir()
+		setTestHome(t, tempHome)
+		configHome := filepath.Join(tempHome, "xdg")
+		t.Setenv("XDG_CONFIG_HOME", configHome)
+
+		if dir, err := GetGlobalDirectory(); err != nil || dir != filepath.Join(configHome, "hx") {
+			t.Fatalf("expected %s, got %s (%v)", filepath.Join(configHome, "hx"), dir, err)
+		}
+	})
+}
+
+func TestPrepareGlobalDirectory(t *testing.T) {
+	writeLegacy := func(t *testing.T, home string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(home, ManifestConfigFile), []byte(`{"organization_id": "org_legacy"}`), 0o600); err != nil {
+			t.Fatalf("unexpected error writing legacy config: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(home, ManifestSecretFile), []byte(`{"secret_key_id": 1}`), 0o600); err != nil {
+			t.Fatalf("unexpected error writing legacy key: %v", err)
+		}
+		// Contexts were never kept in the home directory by a released CLI.
+		if err := os.MkdirAll(filepath.Join(home, ContextsDirectory, "client"), 0o700); err != nil {
+			t.Fatalf("unexpected error writing contexts: %v", err)
+		}
+	}
+
+	t.Run("copies_legacy_files_once", func(t *testing.T) {
+		tempHome := t.TempDir()
+		setTestHome(t, tempHome)
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(tempHome, "xdg"))
+		writeLegacy(t, tempHome)
+
+		dir, copied, err := PrepareGlobalDirectory()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(copied) != 2 {
+			t.Fatalf("expected %s and %s to be copied, got %v", ManifestConfigFile, ManifestSecretFile, copied)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ManifestSecretFile)); err != nil {
+			t.Fatalf("expected %s to be copied: %v", ManifestSecretFile, err)
+		}
+		if _, err := os.Stat(filepath.Join(tempHome, ManifestConfigFile)); err != nil {
+			t.Fatal("expected the legacy .hx to be left for older CLIs")
+		}
+		cfg, err := RestoreGlobalConfig()
+		if err != nil || cfg.OrganizationId != "org_legacy" {
+			t.Fatalf("expected the copied config to be read, got %+v (%v)", cfg, err)
+		}
+		if ContextExists("client") {
+			t.Fatalf("expected %s not to be copied", ContextsDirectory)
+		}
+
+		// Logging out removes the new copy; it must not come back.
+		if err := os.Remove(filepath.Join(dir, ManifestConfigFile)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, copied, err := PrepareGlobalDirectory(); err != nil || len(copied) != 0 {
+			t.Fatalf("expected nothing to be copied again, got %v (%v)", copied, err)
+		}
+	})
+
+	t.Run("override_is_isolated", func(t *testing.T) {
+		tempHome := t.TempDir()
+		setTestHome(t, tempHome)
+		writeLegacy(t, tempHome)
+		override := filepath.Join(t.TempDir(), "ci", "hx")
+		t.Setenv(ConfigDirEnv, override)
+
+		dir, copied, err := PrepareGlobalDirectory()
+		if err != nil || dir != override || len(copied) != 0 {
+			t.Fatalf("expected an empty %s, got %s with %v (%v)", override, dir, copied, err)
+		}
+		if info, err := os.Stat(override); err != nil || !info.IsDir() {
+			t.Fatalf("expected %s to be created: %v", override, err)
+		}
+		if _, err := RestoreGlobalConfig(); !os.IsNotExist(err) {
+			t.Fatalf("expected no global config in %s, got %v", override, err)
+		}
+	})
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Hyphen/cli/pkg/errors"
+)
+
+// Scope says which .hx file a value is read from or written to.
+type Scope int
+
+const (
+	// ScopeAny is used for keys that may live in either file; when reading
+	// it means the effective, merged value.
+	ScopeAny Scope = iota
+	ScopeGlobal
+	ScopeLocal
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeGlobal:
+		return "global"
+	case ScopeLocal:
+		return "local"
+	default:
+		return "any"
+	}
+}
+
+// Key describes a setting of Config that can be read and changed with
+// `hx config`.
+type Key struct {
+	Name        string
+	Description string
+	// Scope restricts where the key may be set. Credentials and endpoints
+	// are global only, so a committed project .hx can't override them.
+	Scope Scope
+	// Secret values are masked when shown.
+	Secret bool
+	// ReadOnly keys are managed by other commands, such as `hx auth`.
+	ReadOnly bool
+
+	get   func(c *Config) (string, bool)
+	set   func(c *Config, value string) error
+	unset func(c *Config)
+}
+
+// Get returns the key's value in c, and false if it isn't set.
+func (k Key) Get(c Config) (string, bool) {
+	return k.get(&c)
+}
+
+// Set validates value and stores it in c.
+func (k Key) Set(c *Config, value string) error {
+	if k.ReadOnly {
+		return fmt.Errorf("%s is managed by the CLI and can't be set", k.Name)
+	}
+	return k.set(c, value)
+}
+
+// Unset removes the key's value from c.
+func (k Key) Unset(c *Config) error {
+	if k.ReadOnly {
+		return fmt.Errorf("%s is managed by the CLI and can't be unset", k.Name)
+	}
+	k.unset(c)
+	return nil
+}
+
+var keys = []Key{
+	{
+		Name:        "organization_id",
+		Description: "Default organization ID (e.g., org_123)",
+		get:         func(c *Config) (string, bool) { return c.OrganizationId, c.OrganizationId != "" },
+		set: func(c *Config, value string) error {
+			if err := validateID(value, "org_"); err != nil {
+				return err
+			}
+			c.OrganizationId = value
+			return nil
+		},
+		unset: func(c *Config) { c.OrganizationId = "" },
+	},
+	idKey("project_id", "Default project ID (e.g., proj_123)", "proj_", func(c *Config) **string { return &c.ProjectId }),
+	stringKey("project_name", "Name of the default project", func(c *Config) **string { return &c.ProjectName }),
+	stringKey("project_alternate_id", "Alternate ID of the default project", func(c *Config) **string { return &c.ProjectAlternateId }),
+	idKey("app_id", "App ID (e.g., app_123)", "app_", func(c *Config) **string { return &c.AppId }),
+	stringKey("app_name", "Name of the app", func(c *Config) **string { return &c.AppName }),
+	stringKey("app_alternate_id", "Alternate ID of the app", func(c *Config) **string { return &c.AppAlternateId }),
+	scoped(boolKey("is_monorepo", "Whether the project is a monorepo", func(c *Config) **bool { return &c.IsMonorepo }), ScopeLocal),
+	scoped(boolKey("auto_update_disabled", "Disable automatic CLI updates", func(c *Config) **bool { return &c.AutoUpdateDisabled }), ScopeGlobal),
+	secret(scoped(stringKey("hyphen_api_key", "API key used instead of signing in", func(c *Config) **string { return &c.HyphenAPIKey }), ScopeGlobal)),
+	endpointKey("endpoints.api", "Base URL of the Hyphen API", func(e *Endpoints) *string { return &e.Api }),
+	endpointKey("endpoints.horizon", "Base URL of Horizon", func(e *Endpoints) *string { return &e.Horizon }),
+	endpointKey("endpoints.app", "Base URL of the Hyphen app", func(e *Endpoints) *string { return &e.App }),
+	endpointKey("endpoints.auth", "Base URL of Hyphen authentication", func(e *Endpoints) *string { return &e.Auth }),
+	endpointKey("endpoints.vinz", "Base URL of Hyphen's key store", func(e *Endpoints) *string { return &e.Vinz }),
+	readOnly(secret(stringKey("hyphen_access_token", "Access token from `hx auth`", func(c *Config) **string { return &c.HyphenAccessToken }))),
+	readOnly(secret(stringKey("hyphen_refresh_token", "Refresh token from `hx auth`", func(c *Config) **string { return &c.HyphenRefreshToken }))),
+	readOnly(secret(stringKey("hyphen_id_token", "ID token from `hx auth`", func(c *Config) **string { return &c.HypenIDToken }))),
+	readOnly(Key{
+		Name:        "expiry_time",
+		Description: "When the access token expires (Unix time)",
+		Scope:       ScopeGlobal,
+		get: func(c *Config) (string, bool) {
+			if c.ExpiryTime == nil {
+				return "", false
+			}
+			return strconv.FormatInt(*c.ExpiryTime, 10), true
+		},
+	}),
+}
+
+// Keys returns every key `hx config` knows, in display order.
+func Keys() []Key {
+	return keys
+}
+
+// LookupKey finds a key by name.
+func LookupKey(name string) (Key, error) {
+	for _, k := range keys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("unknown config key '%s'. Run `hx config list --all` to see every key", name)
+}
+
+func stringKey(name, description string, field func(*Config) **string) Key {
+	return Key{
+		Name:        name,
+		Description: description,
+		get: func(c *Config) (string, bool) {
+			if v := *field(c); v != nil {
+				return *v, true
+			}
+			return "", false
+		},
+		set: func(c *Config, value string) error {
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("%s can't be empty; use `hx config unset %s` to remove it", name, name)
+			}
+			*field(c) = &value
+			return nil
+		},
+		unset: func(c *Config) { *field(c) = nil },
+	}
+}
+
+func idKey(name, description, prefix string, field func(*Config) **string) Key {
+	k := stringKey(name, description, field)
+	set := k.set
+	k.set = func(c *Config, value string) error {
+		if err := validateID(value, prefix); err != nil {
+			return err
+		}
+		return set(c, value)
+	}
+	return k
+}
+
+func boolKey(name, description string, field func(*Config) **bool) Key {
+	return Key{
+		Name:        name,
+		Description: description,
+		get: func(c *Config) (string, bool) {
+			if v := *field(c); v != nil {
+				return strconv.FormatBool(*v), true
+			}
+			return "", false
+		},
+		set: func(c *Config, value string) error {
+			b, err := parseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*field(c) = &b
+			return nil
+		},
+		unset: func(c *Config) { *field(c) = nil },
+	}
+}
+
+func endpointKey(name, description string, field func(*Endpoints) *string) Key {
+	return Key{
+		Name:        name,
+		Description: description,
+		Scope:       ScopeGlobal,
+		get: func(c *Config) (string, bool) {
+			if c.Endpoints == nil || *field(c.Endpoints) == "" {
+				return "", false
+			}
+			return *field(c.Endpoints), true
+		},
+		set: func(c *Config, value string) error {
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("%s must be an http or https URL, not '%s'", name, value)
+			}
+			if c.Endpoints == nil {
+				c.Endpoints = &Endpoints{}
+			}
+			*field(c.Endpoints) = strings.TrimRight(value, "/")
+			return nil
+		},
+		unset: func(c *Config) {
+			if c.Endpoints == nil {
+				return
+			}
+			*field(c.Endpoints) = ""
+			if *c.Endpoints == (Endpoints{}) {
+				c.Endpoints = nil
+			}
+		},
+	}
+}
+
+func scoped(k Key, scope Scope) Key {
+	k.Scope = scope
+	return k
+}
+
+func secret(k Key) Key {
+	k.Secret = true
+	return k
+}
+
+func readOnly(k Key) Key {
+	k.ReadOnly = true
+	k.Scope = ScopeGlobal
+	return k
+}
+
+func validateID(value, prefix string) error {
+	if !strings.HasPrefix(value, prefix) || len(value) == len(prefix) || strings.ContainsAny(value, " \t\n/") {
+		return fmt.Errorf("'%s' is not a valid ID; expected something like %s123", value, prefix)
+	}
+	return nil
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "on", "yes", "1":
+		return true, nil
+	case "false", "off", "no", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid value %q. Expected true or false", value)
+	}
+}
+
+// ConfigFile returns the path of the .hx file for a scope: the active
+// context's global file, or the project's local one.
+func ConfigFile(scope Scope) (string, error) {
+	if scope == ScopeGlobal {
+		return globalConfigPath()
+	}
+	return ManifestConfigFile, nil
+}
+
+// ReadConfigFile reads a single .hx file without merging it with the other.
+// A missing file reads as an empty configuration.
+func ReadConfigFile(scope Scope) (Config, error) {
+	path, err := ConfigFile(scope)
+	if err != nil {
+		return Config{}, err
+	}
+	c, err := readAndUnmarshalConfigJSON[Config](path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	return c, err
+}
+
+// WriteConfigFile replaces a single .hx file.
+func WriteConfigFile(scope Scope, c Config) error {
+	path, err := ConfigFile(scope)
+	if err != nil {
+		return err
+	}
+	if scope == ScopeGlobal {
+		dir, err := GlobalConfigDirectory()
+		if err != nil {
+			return err
+		}
+		if err := FS.MkdirAll(dir, 0o755); err != nil {
+			return errors.Wrap(err, "Failed to create global directory")
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Error encoding JSON")
+	}
+	if err := FS.WriteFile(path, jsonData, 0o644); err != nil {
+		return errors.Wrapf(err, "Error writing file: %s", path)
+	}
+	return nil
+}
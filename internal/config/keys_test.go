@@ -0,0 +1,73 @@
+package config
+
+import "testing"
+
+func TestKeys(t *testing.T) {
+	mustKey := func(t *testing.T, name string) Key {
+		t.Helper()
+		k, err := LookupKey(name)
+		if err != nil {
+			t.Fatalf("unexpected error looking up %s: %v", name, err)
+		}
+		return k
+	}
+
+	t.Run("validates_ids", func(t *testing.T) {
+		var c Config
+		k := mustKey(t, "project_id")
+		if err := k.Set(&c, "org_123"); err == nil {
+			t.Fatal("expected an error for an ID of the wrong kind")
+		}
+		if err := k.Set(&c, "proj_123"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v, ok := k.Get(c); !ok || v != "proj_123" {
+			t.Fatalf("expected proj_123, got %q", v)
+		}
+		if err := k.Unset(&c); err != nil || c.ProjectId != nil {
+			t.Fatalf("expected project_id to be removed, got %v", err)
+		}
+	})
+
+	t.Run("parses_bools", func(t *testing.T) {
+		var c Config
+		k := mustKey(t, "auto_update_disabled")
+		if err := k.Set(&c, "maybe"); err == nil {
+			t.Fatal("expected an error for an invalid bool")
+		}
+		if err := k.Set(&c, "on"); err != nil || c.AutoUpdateDisabled == nil || !*c.AutoUpdateDisabled {
+			t.Fatalf("expected auto_update_disabled to be true, got %v", err)
+		}
+	})
+
+	t.Run("endpoints_are_urls", func(t *testing.T) {
+		var c Config
+		k := mustKey(t, "endpoints.api")
+		if k.Scope != ScopeGlobal {
+			t.Fatal("expected endpoints to be global only")
+		}
+		if err := k.Set(&c, "dev-api.hyphen.ai"); err == nil {
+			t.Fatal("expected an error for a URL without a scheme")
+		}
+		if err := k.Set(&c, "https://dev-api.hyphen.ai/"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.Endpoints == nil || c.Endpoints.Api != "https://dev-api.hyphen.ai" {
+			t.Fatalf("expected the endpoint to be stored, got %+v", c.Endpoints)
+		}
+		if err := k.Unset(&c); err != nil || c.Endpoints != nil {
+			t.Fatalf("expected empty endpoints to be removed, got %+v", c.Endpoints)
+		}
+	})
+
+	t.Run("tokens_are_read_only", func(t *testing.T) {
+		var c Config
+		if err := mustKey(t, "hyphen_access_token").Set(&c, "token"); err == nil {
+			t.Fatal("expected an error setting a token")
+		}
+	})
+
+	if _, err := LookupKey("nope"); err == nil {
+		t.Fatal("expected an error for an unknown key")
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/Hyphen/cli/pkg/errors"
+)
+
+const gitDirPath = ".git"
+
+// invocationDirectory is the directory the CLI was started in, before
+// EnterLocalRoot moved to the app root.
+var invocationDirectory string
+
+// FindLocalRoot searches startPath and its parents for a local .hx, the way
+// git finds its repository. The search stops at the root of the git
+// repository or of the filesystem, and never treats a .hx left in the home
+// directory by older CLIs as a project's.
+func FindLocalRoot(startPath string) (string, bool) {
+	home := homeDirectory()
+	currentPath := startPath
+	for {
+		if currentPath != startPath && currentPath == home {
+			return "", false
+		}
+		if info, err := FS.Stat(filepath.Join(currentPath, ManifestConfigFile)); err == nil && !info.IsDir() {
+			return currentPath, true
+		}
+		if _, err := FS.Stat(filepath.Join(currentPath, gitDirPath)); err == nil {
+			return "", false
+		}
+
+		parent := filepath.Dir(currentPath)
+		if parent == currentPath {
+			return "", false
+		}
+		currentPath = parent
+	}
+}
+
+// EnterLocalRoot changes the working directory to the app root found by
+// FindLocalRoot, so .hx, .hxkey and env files resolve as if the command were
+// run there. It returns the app root, or "" if there is none and the working
+// directory was left alone.
+func EnterLocalRoot() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get the current directory")
+	}
+	invocationDirectory = cwd
+
+	root, found := FindLocalRoot(cwd)
+	if !found {
+		return "", nil
+	}
+	if root != cwd {
+		if err := os.Chdir(root); err != nil {
+			return "", errors.Wrapf(err, "Failed to change to the app root %s", root)
+		}
+	}
+	return root, nil
+}
+
+// InvocationDirectory returns the directory the CLI was started in, which
+// differs from the working directory when run below an app root.
+func InvocationDirectory() string {
+	if invocationDirectory != "" {
+		return invocationDirectory
+	}
+	cwd, _ := os.Getwd()
+	return cwd
+}
+
+// ResolveInvocationPath makes a path given on the command line relative to
+// the directory the CLI was started in rather than the app root.
+func ResolveInvocationPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(InvocationDirectory(), path)
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindLocalRoot(t *testing.T) {
+	tempHome, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error resolving temp dir: %v", err)
+	}
+	setTestHome(t, tempHome)
+
+	mkdir := func(t *testing.T, parts ...string) string {
+		t.Helper()
+		dir := filepath.Join(append([]string{tempHome}, parts...)...)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("unexpected error creating %s: %v", dir, err)
+		}
+		return dir
+	}
+	touch := func(t *testing.T, path string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatalf("unexpected error writing %s: %v", path, err)
+		}
+	}
+
+	touch(t, filepath.Join(tempHome, ManifestConfigFile))
+
+	app := mkdir(t, "repo", "app")
+	mkdir(t, "repo", ".git")
+	touch(t, filepath.Join(app, ManifestConfigFile))
+	src := mkdir(t, "repo", "app", "src", "pkg")
+
+	t.Run("walks_up_to_the_app", func(t *testing.T) {
+		root, found := FindLocalRoot(src)
+		if !found || root != app {
+			t.Fatalf("expected %s, got %q (found %v)", app, root, found)
+		}
+	})
+
+	t.Run("stops_at_the_git_root", func(t *testing.T) {
+		other := mkdir(t, "repo", "other")
+		if root, found := FindLocalRoot(other); found {
+			t.Fatalf("expected no app root, got %s", root)
+		}
+	})
+
+	t.Run("ignores_the_global_config", func(t *testing.T) {
+		loose := mkdir(t, "scratch", "dir")
+		if root, found := FindLocalRoot(loose); found {
+			t.Fatalf("expected no app root, got %s", root)
+		}
+	})
+
+	t.Run("enters_the_app_root", func(t *testing.T) {
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = os.Chdir(wd)
+			invocationDirectory = ""
+		})
+		if err := os.Chdir(src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		root, err := EnterLocalRoot()
+		if err != nil || root != app {
+			t.Fatalf("expected to enter %s, got %q (%v)", app, root, err)
+		}
+		if cwd, _ := os.Getwd(); cwd != app {
+			t.Fatalf("expected working directory %s, got %s", app, cwd)
+		}
+		if !ExistsLocal() {
+			t.Fatal("expected the app's .hx to be found")
+		}
+		if got, want := ResolveInvocationPath("vars.json"), filepath.Join(src, "vars.json"); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	})
+}
@@ -4,9 +4,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"os"
+	"path/filepath"
 
 	"github.com/Hyphen/cli/internal/config"
 	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/fsutil"
 )
 
 type Database interface {
@@ -77,6 +80,15 @@ func (db *database) UpsertSecret(key SecretKey, data string, version int) error
 }
 
 func (db *database) UpsertSecrets(updates []SecretUpdate) error {
+	secrets, err := save(updates)
+	if err != nil {
+		return err
+	}
+	db.Secrets = secrets
+	return nil
+}
+
+func (db *database) apply(updates []SecretUpdate) {
 	if db.Secrets == nil {
 		db.Secrets = make(map[string]map[string]map[string]Secret)
 	}
@@ -94,61 +106,169 @@ func (db *database) UpsertSecrets(updates []SecretUpdate) error {
 
 		db.Secrets[key.ProjectId][key.AppId][key.EnvName] = newSecret(update.Data, update.Version)
 	}
+}
 
-	return save(*db)
+// StateFile is the file, in the state directory, the database is kept in.
+var StateFile = "state.json"
+
+// StateDirectory returns the directory hx keeps local state in:
+// $HX_CONFIG_DIR/state, $XDG_STATE_HOME/hx, or ~/.local/state/hx.
+func StateDirectory() (string, error) {
+	if os.Getenv(config.ConfigDirEnv) != "" {
+		dir, err := config.GetGlobalDirectory()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "state"), nil
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "hx"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to find your home directory")
+	}
+	return filepath.Join(home, ".local", "state", "hx"), nil
 }
 
+// statePath returns the state file of the active context. Named contexts
+// keep theirs in a directory of their own, so they never share the versions
+// recorded for an organization they can't access.
+func statePath() (string, error) {
+	dir, err := StateDirectory()
+	if err != nil {
+		return "", err
+	}
+	name, err := config.ResolveActiveContext()
+	if err != nil {
+		return "", err
+	}
+	if name != config.DefaultContext {
+		dir = filepath.Join(dir, "contexts", name)
+	}
+	return filepath.Join(dir, StateFile), nil
+}
+
+// Restore loads the database from the state store, first moving it out of
+// the global .hx file if an older CLI left it there.
 func Restore() (Database, error) {
-	m, err := config.RestoreConfig()
+	var db database
+	err := withLock(func() error {
+		var err error
+		db, err = load()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	return &db, nil
+}
 
-	// Check if the Database field is nil, and initialize if necessary
-	if m.Database == nil {
-		// Initialize a new database with an empty Secrets map
-		newDB := &database{
-			Secrets: make(map[string]map[string]map[string]Secret),
-		}
+// load reads the state file. It must be called with the lock held.
+func load() (database, error) {
+	path, err := statePath()
+	if err != nil {
+		return database{}, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return migrateLegacy()
+	}
+	if err != nil {
+		return database{}, errors.Wrapf(err, "Failed to read %s", path)
+	}
 
-		// Save the newly initialized database back to the manifest
-		if err := save(*newDB); err != nil {
-			return nil, err
-		}
+	var db database
+	if err := json.Unmarshal(data, &db); err != nil {
+		return database{}, errors.Wrapf(err, "Error decoding JSON file: %s", path)
+	}
+	if db.Secrets == nil {
+		db.Secrets = make(map[string]map[string]map[string]Secret)
+	}
+	return db, nil
+}
+
+// migrateLegacy moves the database older CLIs kept in the global .hx file to
+// the state file, and removes it from .hx. It must be called with the lock
+// held.
+func migrateLegacy() (database, error) {
+	db := database{Secrets: make(map[string]map[string]map[string]Secret)}
+
+	global, err := config.RestoreGlobalConfig()
+	if err != nil || global.Database == nil {
+		return db, nil
+	}
 
-		// Assign the new database to the manifest
-		m.Database = *newDB
+	dbBytes, err := json.Marshal(global.Database)
+	if err != nil {
+		return database{}, err
+	}
+	if err := json.Unmarshal(dbBytes, &db); err != nil {
+		return database{}, errors.New("unexpected type found in .hx file")
+	}
+	if db.Secrets == nil {
+		db.Secrets = make(map[string]map[string]map[string]Secret)
 	}
 
-	// Attempt to convert m.Database to a database struct
+	if err := write(db); err != nil {
+		return database{}, err
+	}
+	global.Database = nil
+	if err := config.UpsertGlobalConfig(global); err != nil {
+		return database{}, errors.Wrap(err, "Failed to remove the database from the global .hx file")
+	}
+	return db, nil
+}
+
+// save merges the updates into the latest state on disk, so concurrent hx
+// processes don't drop each other's changes, and returns the merged secrets.
+func save(updates []SecretUpdate) (map[string]map[string]map[string]Secret, error) {
 	var db database
-	switch v := m.Database.(type) {
-	case database:
-		db = v
-	case map[string]interface{}:
-		// Convert the map to JSON
-		dbBytes, err := json.Marshal(v)
-		if err != nil {
-			return nil, err
+	err := withLock(func() error {
+		var err error
+		if db, err = load(); err != nil {
+			return err
 		}
+		db.apply(updates)
+		return write(db)
+	})
+	return db.Secrets, err
+}
 
-		// Unmarshal the JSON into a database struct
-		if err := json.Unmarshal(dbBytes, &db); err != nil {
-			return nil, err
-		}
-	default:
-		return nil, errors.New("unexpected type found in .hx file")
+// write replaces the state file atomically, so a crash never leaves it half
+// written. It must be called with the lock held.
+func write(db database) error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Error encoding JSON")
 	}
 
-	return &db, nil
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(path, data, 0o600); err != nil {
+		return errors.Wrapf(err, "Failed to write %s", path)
+	}
+	return nil
 }
 
-func save(db database) error {
-	mc, err := config.RestoreConfig()
+// withLock runs fn holding an exclusive lock on the state store, shared by
+// every hx process of the user.
+func withLock(fn func() error) error {
+	path, err := statePath()
 	if err != nil {
 		return err
 	}
-	mc.Database = db
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return errors.Wrap(err, "Failed to create state directory")
+	}
+
+	unlock, err := fsutil.LockFile(path + ".lock")
+	if err != nil {
+		return errors.Wrap(err, "Failed to lock the state store")
+	}
+	defer unlock()
 
-	return config.UpsertGlobalConfig(mc)
+	return fn()
 }
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Hyphen/cli/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupState(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(config.ConfigDirEnv, "")
+	t.Setenv(config.ContextEnv, "")
+	return home
+}
+
+func TestRestoreMigratesLegacyDatabase(t *testing.T) {
+	home := setupState(t)
+	legacy := `{
+  "organization_id": "org_1",
+  "database": {"secrets": {"project": {"app": {"default": {"version": 3, "hash": "abc"}}}}}
+}`
+	assert.NoError(t, os.WriteFile(filepath.Join(home, ".hx"), []byte(legacy), 0o644))
+	globalDir, _, err := config.PrepareGlobalDirectory()
+	assert.NoError(t, err)
+
+	db, err := Restore()
+	assert.NoError(t, err)
+	secret, ok := db.GetSecret(SecretKey{ProjectId: "project", AppId: "app", EnvName: "default"})
+	assert.True(t, ok)
+	assert.Equal(t, Secret{Version: 3, Hash: "abc"}, secret)
+
+	_, err = os.Stat(filepath.Join(home, ".local", "state", "hx", StateFile))
+	assert.NoError(t, err)
+
+	var global map[string]interface{}
+	data, err := os.ReadFile(filepath.Join(globalDir, ".hx"))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &global))
+	assert.NotContains(t, global, "database")
+	assert.Equal(t, "org_1", global["organization_id"])
+}
+
+func TestUpsertKeepsOtherProcessesChanges(t *testing.T) {
+	setupState(t)
+	first, err := Restore()
+	assert.NoError(t, err)
+	second, err := Restore()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i, db := range []Database{first, second} {
+		wg.Add(1)
+		go func(i int, db Database) {
+			defer wg.Done()
+			key := SecretKey{ProjectId: "project", AppId: "app", EnvName: []string{"staging", "production"}[i]}
+			assert.NoError(t, db.UpsertSecret(key, "A=1", i+1))
+		}(i, db)
+	}
+	wg.Wait()
+
+	db, err := Restore()
+	assert.NoError(t, err)
+	for _, envName := range []string{"staging", "production"} {
+		_, ok := db.GetSecret(SecretKey{ProjectId: "project", AppId: "app", EnvName: envName})
+		assert.True(t, ok, envName)
+	}
+}
+
+func TestStateDirectory(t *testing.T) {
+	home := setupState(t)
+	dir, err := StateDirectory()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".local", "state", "hx"), dir)
+
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+	dir, err = StateDirectory()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(state, "hx"), dir)
+}
+
+func TestStateIsScopedByContext(t *testing.T) {
+	home := setupState(t)
+	assert.NoError(t, config.CreateContext("client", config.Config{}))
+	key := SecretKey{ProjectId: "project", AppId: "app", EnvName: "default"}
+
+	t.Setenv(config.ContextEnv, "client")
+	db, err := Restore()
+	assert.NoError(t, err)
+	assert.NoError(t, db.UpsertSecret(key, "A=1", 2))
+	_, err = os.Stat(filepath.Join(home, ".local", "state", "hx", "contexts", "client", StateFile))
+	assert.NoError(t, err)
+
+	t.Setenv(config.ContextEnv, "")
+	db, err = Restore()
+	assert.NoError(t, err)
+	_, ok := db.GetSecret(key)
+	assert.False(t, ok)
+
+	t.Setenv(config.ContextEnv, "missing")
+	_, err = Restore()
+	assert.Error(t, err)
+}
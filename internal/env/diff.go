@@ -0,0 +1,60 @@
+package env
+
+import (
+	"sort"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// VariableChange describes how a single key differs between two envs.
+type VariableChange struct {
+	Key      string     `json:"key"`
+	Type     ChangeType `json:"type"`
+	OldValue string     `json:"oldValue,omitempty"`
+	NewValue string     `json:"newValue,omitempty"`
+}
+
+// maskedValue is shown in place of secret values. It has a fixed width so
+// the mask does not leak the length of the value.
+const maskedValue = "********"
+
+// MaskValue hides a secret value for display. Empty values stay empty so
+// "set to nothing" is still distinguishable from "set".
+func MaskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+// DiffVariables returns the key-level changes needed to turn from into to,
+// sorted by key.
+func DiffVariables(from, to map[string]string) []VariableChange {
+	var changes []VariableChange
+	for key, oldValue := range from {
+		newValue, ok := to[key]
+		if !ok {
+			changes = append(changes, VariableChange{Key: key, Type: ChangeRemoved, OldValue: oldValue})
+			continue
+		}
+		if newValue != oldValue {
+			changes = append(changes, VariableChange{Key: key, Type: ChangeChanged, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for key, newValue := range to {
+		if _, ok := from[key]; !ok {
+			changes = append(changes, VariableChange{Key: key, Type: ChangeAdded, NewValue: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
@@ -0,0 +1,32 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffVariables(t *testing.T) {
+	from := map[string]string{"SAME": "1", "CHANGED": "old", "REMOVED": "gone"}
+	to := map[string]string{"SAME": "1", "CHANGED": "new", "ADDED": "here"}
+
+	changes := DiffVariables(from, to)
+
+	assert.Equal(t, []VariableChange{
+		{Key: "ADDED", Type: ChangeAdded, NewValue: "here"},
+		{Key: "CHANGED", Type: ChangeChanged, OldValue: "old", NewValue: "new"},
+		{Key: "REMOVED", Type: ChangeRemoved, OldValue: "gone"},
+	}, changes)
+}
+
+func TestDiffVariablesNoChanges(t *testing.T) {
+	vars := map[string]string{"KEY": "value"}
+
+	assert.Empty(t, DiffVariables(vars, vars))
+}
+
+func TestMaskValue(t *testing.T) {
+	assert.Equal(t, "", MaskValue(""))
+	assert.Equal(t, "********", MaskValue("a"))
+	assert.Equal(t, "********", MaskValue("a-much-longer-secret-value"))
+}
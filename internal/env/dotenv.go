@@ -0,0 +1,214 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variable is a single KEY=VALUE definition parsed from dotenv content.
+type Variable struct {
+	Key   string
+	Value string
+	// Line is the 1-based line the definition starts on.
+	Line int
+	// Quote is the quote character the value was wrapped in, or 0 if unquoted.
+	Quote byte
+	// Source names where the definition came from, such as the file it was
+	// read from. Parse leaves it empty for callers to fill in.
+	Source string
+
+	// endLine is the 1-based line the definition ends on; it differs from
+	// Line only for quoted values that span several lines.
+	endLine int
+	// escapedDollars holds the offsets in Value of '$' characters written as
+	// `\$`, which must not start an interpolation.
+	escapedDollars []int
+}
+
+// ParseError reports malformed dotenv content.
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Parse parses dotenv content into its variable definitions, in file order.
+// It understands comments, blank lines, an optional `export` prefix, single
+// and double quoted values (which may span lines), escapes inside double
+// quotes and inline comments after unquoted values. Duplicate keys are all
+// returned; the last definition is the effective one.
+//
+// On malformed content Parse returns the definitions read so far together
+// with a *ParseError.
+func Parse(content string) ([]Variable, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+
+	var vars []Variable
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if isConflictMarker(line) {
+			return vars, &ParseError{Line: lineNum, Message: "unresolved merge conflict; edit the file to keep one value and remove the markers"}
+		}
+
+		if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+
+		key, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return vars, &ParseError{Line: lineNum, Message: fmt.Sprintf("expected KEY=VALUE, got %q", line)}
+		}
+		key = strings.TrimSpace(key)
+		if !validKeyRegex.MatchString(key) {
+			return vars, &ParseError{Line: lineNum, Message: fmt.Sprintf("invalid variable name %q", key)}
+		}
+
+		v := Variable{Key: key, Line: lineNum, endLine: lineNum}
+		trimmed := strings.TrimLeft(rawValue, " \t")
+
+		if trimmed != "" && (trimmed[0] == '"' || trimmed[0] == '\'') {
+			quote := trimmed[0]
+			value, escapedDollars, rest, endIdx, err := readQuoted(quote, trimmed[1:], lines, i)
+			if err != nil {
+				return vars, &ParseError{Line: lineNum, Message: err.Error()}
+			}
+			rest = strings.TrimSpace(rest)
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return vars, &ParseError{Line: endIdx + 1, Message: fmt.Sprintf("unexpected characters after closing quote: %q", rest)}
+			}
+			v.Value = value
+			v.escapedDollars = escapedDollars
+			v.Quote = quote
+			v.endLine = endIdx + 1
+			i = endIdx
+		} else {
+			v.Value = stripInlineComment(rawValue)
+		}
+
+		vars = append(vars, v)
+	}
+
+	return vars, nil
+}
+
+// readQuoted reads a quoted value starting just after the opening quote on
+// lines[start]. It returns the unescaped value, the offsets of escaped '$'
+// characters in it, whatever follows the closing quote on its line, and the
+// index of the line the value ends on.
+func readQuoted(quote byte, first string, lines []string, start int) (string, []int, string, int, error) {
+	var b strings.Builder
+	var escapedDollars []int
+	text := first
+	for idx := start; ; {
+		for j := 0; j < len(text); j++ {
+			c := text[j]
+			switch {
+			case c == quote:
+				return b.String(), escapedDollars, text[j+1:], idx, nil
+			case c == '\\' && quote == '"' && j+1 < len(text):
+				j++
+				switch text[j] {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				case '$':
+					escapedDollars = append(escapedDollars, b.Len())
+					b.WriteByte('$')
+				case '"', '\\':
+					b.WriteByte(text[j])
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(text[j])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+
+		idx++
+		if idx >= len(lines) {
+			return "", nil, "", idx, fmt.Errorf("unterminated %c-quoted value", quote)
+		}
+		b.WriteByte('\n')
+		text = lines[idx]
+	}
+}
+
+// stripInlineComment removes a trailing `# comment` from an unquoted value,
+// given with the whitespace after '='. A '#' only starts a comment when
+// preceded by whitespace, so `KEY=#value` and URLs with fragments survive
+// while `KEY= # note` is empty.
+func stripInlineComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+// Variables returns the effective key/value pairs defined in dotenv content.
+// Later definitions of the same key win.
+func Variables(content string) (map[string]string, error) {
+	parsed, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string, len(parsed))
+	for _, v := range parsed {
+		vars[v.Key] = v.Value
+	}
+	return vars, nil
+}
+
+// Environ returns the effective variables in dotenv content as KEY=VALUE
+// strings, in the order each key was first defined, suitable for exec.Cmd.Env.
+func Environ(content string) ([]string, error) {
+	parsed, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return EffectiveEnviron(parsed), nil
+}
+
+// Effective returns the effective definition of each key in defs, in the
+// order each key was first defined.
+func Effective(defs []Variable) []Variable {
+	index := make(map[string]int, len(defs))
+	var effective []Variable
+	for _, v := range defs {
+		if i, seen := index[v.Key]; seen {
+			effective[i] = v
+			continue
+		}
+		index[v.Key] = len(effective)
+		effective = append(effective, v)
+	}
+	return effective
+}
+
+// EffectiveEnviron returns KEY=VALUE strings for the effective definition of
+// each key in defs, in the order each key was first defined. Values are used
+// as-is, without interpolation.
+func EffectiveEnviron(defs []Variable) []string {
+	effective := Effective(defs)
+	environ := make([]string, 0, len(effective))
+	for _, v := range effective {
+		environ = append(environ, v.Key+"="+v.Value)
+	}
+	return environ
+}
@@ -0,0 +1,87 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]string
+	}{
+		{"plain", "KEY=value", map[string]string{"KEY": "value"}},
+		{"trims_spaces", "  KEY = value  ", map[string]string{"KEY": "value"}},
+		{"empty_value", "KEY=", map[string]string{"KEY": ""}},
+		{"comments_and_blank_lines", "# comment\n\nKEY=value\n  # indented comment\n", map[string]string{"KEY": "value"}},
+		{"export_prefix", "export KEY=value", map[string]string{"KEY": "value"}},
+		{"export_as_key", "export=value", map[string]string{"export": "value"}},
+		{"double_quotes", `KEY="bar"`, map[string]string{"KEY": "bar"}},
+		{"single_quotes", `KEY='bar'`, map[string]string{"KEY": "bar"}},
+		{"quotes_keep_spaces_and_hash", `KEY=" a # b "`, map[string]string{"KEY": " a # b "}},
+		{"double_quote_escapes", `KEY="a\nb\t\"c\"\\d\$e"`, map[string]string{"KEY": "a\nb\t\"c\"\\d$e"}},
+		{"single_quotes_are_literal", `KEY='a\nb'`, map[string]string{"KEY": `a\nb`}},
+		{"multi_line_double", "KEY=\"line1\nline2\"\nNEXT=1", map[string]string{"KEY": "line1\nline2", "NEXT": "1"}},
+		{"multi_line_single", "KEY='line1\n\nline3'", map[string]string{"KEY": "line1\n\nline3"}},
+		{"inline_comment", "KEY=value # comment", map[string]string{"KEY": "value"}},
+		{"comment_as_value", "KEY= # comment\nNEXT=#not-a-comment", map[string]string{"KEY": "", "NEXT": "#not-a-comment"}},
+		{"inline_comment_after_quotes", `KEY="value" # comment`, map[string]string{"KEY": "value"}},
+		{"hash_without_space_is_value", "URL=http://host/#anchor", map[string]string{"URL": "http://host/#anchor"}},
+		{"equals_in_value", "KEY=a=b=c", map[string]string{"KEY": "a=b=c"}},
+		{"duplicates_last_wins", "KEY=1\nKEY=2", map[string]string{"KEY": "2"}},
+		{"crlf", "A=1\r\nB=\"2\"\r\n", map[string]string{"A": "1", "B": "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := Variables(tt.content)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, vars)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+	}{
+		{"missing_equals", "A=1\nNOT_A_VAR", 2},
+		{"invalid_key", "1KEY=value", 1},
+		{"dash_in_key", "A=1\nFOO-BAR=value", 2},
+		{"unterminated_quote", "A=1\nKEY=\"open\nstill open", 2},
+		{"garbage_after_quote", `KEY="value"garbage`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content)
+			var parseErr *ParseError
+			assert.ErrorAs(t, err, &parseErr)
+			assert.Equal(t, tt.line, parseErr.Line)
+		})
+	}
+}
+
+func TestParseRecordsLines(t *testing.T) {
+	vars, err := Parse("# header\nA=1\nB=\"x\ny\"\nC='z'")
+	assert.NoError(t, err)
+	assert.Equal(t, []Variable{
+		{Key: "A", Value: "1", Line: 2, endLine: 2},
+		{Key: "B", Value: "x\ny", Line: 3, Quote: '"', endLine: 4},
+		{Key: "C", Value: "z", Line: 5, Quote: '\'', endLine: 5},
+	}, vars)
+}
+
+func TestEnviron(t *testing.T) {
+	environ, err := Environ("B=1\nA=\"two words\"\nB=3\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"B=3", "A=two words"}, environ)
+}
+
+func TestCountEnvVars(t *testing.T) {
+	assert.Equal(t, 2, countEnvVars("# c\nA=1\nB=\"multi\nline\"\nA=2\n"))
+}
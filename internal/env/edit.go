@@ -0,0 +1,139 @@
+package env
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Hyphen/cli/pkg/errors"
+)
+
+// validKeyRegex is the grammar of variable names, shared by the parser and
+// every command that writes a variable.
+var validKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// ValidateKey checks that key can be used as an environment variable name.
+func ValidateKey(key string) error {
+	if !validKeyRegex.MatchString(key) {
+		return errors.Wrapf(nil, "Invalid variable name '%s'. A valid name starts with a letter or underscore and contains only letters, numbers, underscores and dots", key)
+	}
+	return nil
+}
+
+// FormatValue renders value for the right-hand side of a dotenv line,
+// quoting it when it would not survive unquoted.
+func FormatValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\r\"'#\\$`") {
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`).Replace(value)
+		return fmt.Sprintf(`"%s"`, escaped)
+	}
+	return value
+}
+
+// SetVariable sets key to value in dotenv content. An existing definition is
+// replaced in place and any later duplicates are dropped; otherwise the
+// variable is appended. Comments and the order of other lines are preserved.
+func SetVariable(content, key, value string) (string, error) {
+	return setDefinition(content, key, fmt.Sprintf("%s=%s", key, FormatValue(value)))
+}
+
+// CopyVariable sets key in content to its effective definition in source,
+// copied verbatim so quoting and references keep their meaning. It reports
+// whether source defines key at all.
+func CopyVariable(content, source, key string) (string, bool, error) {
+	parsed, err := Parse(source)
+	if err != nil {
+		return "", false, err
+	}
+
+	var def *Variable
+	for i := range parsed {
+		if parsed[i].Key == key {
+			def = &parsed[i]
+		}
+	}
+	if def == nil {
+		return content, false, nil
+	}
+
+	content, err = setDefinition(content, key, definitionText(source, *def))
+	return content, true, err
+}
+
+// setDefinition is SetVariable for an already rendered definition of key.
+func setDefinition(content, key, newLine string) (string, error) {
+	parsed, err := Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	var defs []Variable
+	for _, v := range parsed {
+		if v.Key == key {
+			defs = append(defs, v)
+		}
+	}
+
+	if len(defs) == 0 {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content + newLine + "\n", nil
+	}
+
+	return replaceDefinitions(content, defs, newLine), nil
+}
+
+// UnsetVariable removes every definition of key from dotenv content and
+// reports whether anything was removed.
+func UnsetVariable(content, key string) (string, bool, error) {
+	parsed, err := Parse(content)
+	if err != nil {
+		return "", false, err
+	}
+
+	var defs []Variable
+	for _, v := range parsed {
+		if v.Key == key {
+			defs = append(defs, v)
+		}
+	}
+
+	if len(defs) == 0 {
+		return content, false, nil
+	}
+
+	return replaceDefinitions(content, defs, ""), true, nil
+}
+
+// definitionText returns the lines of content that v was parsed from.
+func definitionText(content string, v Variable) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	return strings.Join(lines[v.Line-1:v.endLine], "\n")
+}
+
+// replaceDefinitions replaces the lines of the first definition in defs with
+// replacement (or drops them when replacement is empty) and drops the lines of
+// every other definition.
+func replaceDefinitions(content string, defs []Variable, replacement string) string {
+	lines := strings.Split(content, "\n")
+	drop := make(map[int]bool)
+	for _, d := range defs {
+		for line := d.Line; line <= d.endLine; line++ {
+			drop[line-1] = true
+		}
+	}
+
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if i == defs[0].Line-1 && replacement != "" {
+			out = append(out, replacement)
+			continue
+		}
+		if drop[i] {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
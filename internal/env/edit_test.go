@@ -0,0 +1,98 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateKey(t *testing.T) {
+	assert.NoError(t, ValidateKey("DATABASE_URL"))
+	assert.NoError(t, ValidateKey("_private.key"))
+	assert.Error(t, ValidateKey("1KEY"))
+	assert.Error(t, ValidateKey("KEY-NAME"))
+	assert.Error(t, ValidateKey(""))
+}
+
+func TestFormatValue(t *testing.T) {
+	assert.Equal(t, "plain", FormatValue("plain"))
+	assert.Equal(t, `""`, FormatValue(""))
+	assert.Equal(t, `"with space"`, FormatValue("with space"))
+	assert.Equal(t, `"line1\nline2"`, FormatValue("line1\nline2"))
+	assert.Equal(t, `"say \"hi\""`, FormatValue(`say "hi"`))
+}
+
+func mustSetVariable(t *testing.T, content, key, value string) string {
+	t.Helper()
+	out, err := SetVariable(content, key, value)
+	assert.NoError(t, err)
+	return out
+}
+
+func TestSetVariable(t *testing.T) {
+	t.Run("replaces_in_place_and_keeps_comments", func(t *testing.T) {
+		content := "# db\nDB_HOST=localhost\nDB_PORT=5432\n"
+
+		assert.Equal(t, "# db\nDB_HOST=db.internal\nDB_PORT=5432\n", mustSetVariable(t, content, "DB_HOST", "db.internal"))
+	})
+
+	t.Run("appends_new_keys", func(t *testing.T) {
+		assert.Equal(t, "A=1\nB=2\n", mustSetVariable(t, "A=1", "B", "2"))
+		assert.Equal(t, "A=1\nB=2\n", mustSetVariable(t, "A=1\n", "B", "2"))
+		assert.Equal(t, "B=2\n", mustSetVariable(t, "", "B", "2"))
+	})
+
+	t.Run("drops_later_duplicates", func(t *testing.T) {
+		assert.Equal(t, "A=3\nB=2\n", mustSetVariable(t, "A=1\nB=2\nA=1\n", "A", "3"))
+	})
+
+	t.Run("matches_export_prefix", func(t *testing.T) {
+		assert.Equal(t, "A=2\n", mustSetVariable(t, "export A=1\n", "A", "2"))
+	})
+
+	t.Run("replaces_multi_line_values", func(t *testing.T) {
+		content := "CERT=\"line1\nline2\"\nB=2\n"
+
+		assert.Equal(t, "CERT=new\nB=2\n", mustSetVariable(t, content, "CERT", "new"))
+	})
+
+	t.Run("round_trips_through_the_parser", func(t *testing.T) {
+		value := "a \"quoted\" #value\nwith\\newline and $HOME"
+		vars, err := Variables(mustSetVariable(t, "", "K", value))
+		assert.NoError(t, err)
+		assert.Equal(t, value, vars["K"])
+	})
+
+	t.Run("rejects_malformed_content", func(t *testing.T) {
+		_, err := SetVariable("A=\"unterminated\n", "B", "1")
+		assert.Error(t, err)
+	})
+}
+
+func TestUnsetVariable(t *testing.T) {
+	content, removed, err := UnsetVariable("# keep\nA=1\nB=2\nA=3\n", "A")
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	assert.Equal(t, "# keep\nB=2\n", content)
+
+	_, removed, err = UnsetVariable("B=2\n", "A")
+	assert.NoError(t, err)
+	assert.False(t, removed)
+
+	content, removed, err = UnsetVariable("A='multi\nline'\nB=2\n", "A")
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	assert.Equal(t, "B=2\n", content)
+}
+
+func TestCopyVariable(t *testing.T) {
+	content, found, err := CopyVariable("# keep\nA=1\n", "A=\"${HOST}/x\" # note\nB=2\n", "A")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "# keep\nA=\"${HOST}/x\" # note\n", content)
+
+	content, found, err = CopyVariable("A=1\n", "B=2\n", "C")
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, "A=1\n", content)
+}
@@ -13,40 +13,37 @@ import (
 )
 
 func New(fileName string) (models.Env, error) {
-	var data models.Env
-
 	content, err := os.ReadFile(fileName)
 	if err != nil {
-		return data, errors.Wrapf(err, "Failed to open environment file '%s'", fileName)
+		return models.Env{}, errors.Wrapf(err, "Failed to open environment file '%s'", fileName)
 	}
 
-	contentStr := string(content)
+	return NewFromContent(string(content)), nil
+}
 
-	countVariables := countEnvVars(contentStr)
+// NewFromContent builds an unencrypted Env from plain dotenv content.
+func NewFromContent(content string) models.Env {
+	var data models.Env
 
-	data.Size = strconv.Itoa(len(contentStr)) + " bytes"
-	data.CountVariables = countVariables
-	data.Data = contentStr
+	data.Size = strconv.Itoa(len(content)) + " bytes"
+	data.CountVariables = countEnvVars(content)
+	data.Data = content
 	data.Version = nil
 	data.ProjectEnv = nil
 
-	return data, nil
+	return data
 }
 
+// countEnvVars returns the number of distinct variables defined in content.
+// Malformed content is counted up to the first error; callers that need the
+// values themselves report the error through Parse.
 func countEnvVars(content string) int {
-	count := 0
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if IsEnvVar(line) {
-			count++
-		}
+	parsed, _ := Parse(content)
+	keys := make(map[string]struct{}, len(parsed))
+	for _, v := range parsed {
+		keys[v.Key] = struct{}{}
 	}
-	return count
-}
-
-func IsEnvVar(line string) bool {
-	return line != "" && !strings.HasPrefix(line, "#") && strings.Contains(line, "=")
+	return len(keys)
 }
 
 func NewWithEncryptedData(fileName string, secret models.Secret) (models.Env, error) {
@@ -110,7 +107,7 @@ func GetEnvsInDirectory() ([]string, error) {
 	}
 
 	for _, file := range files {
-		if !file.IsDir() && strings.HasPrefix(file.Name(), ".env") && !strings.HasSuffix(file.Name(), ".local") {
+		if !file.IsDir() && strings.HasPrefix(file.Name(), ".env") && !strings.HasSuffix(file.Name(), ".local") && file.Name() != SchemaFileName {
 			envFiles = append(envFiles, file.Name())
 		}
 	}
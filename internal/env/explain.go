@@ -0,0 +1,53 @@
+package env
+
+import "fmt"
+
+// HostSource is the source name used for values from the host environment.
+const HostSource = "host environment"
+
+// Explanation describes how the effective value of a key was chosen from
+// layered definitions.
+type Explanation struct {
+	Key string `json:"key"`
+	// Value is the masked effective value.
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+	// Overrides lists the layers the winning definition shadowed, lowest
+	// precedence first, as "source:line" or HostSource.
+	Overrides []string `json:"overrides"`
+}
+
+// Explain reports, for each key defined in defs, which definition wins and
+// which ones it overrides, in the order each key was first defined. Later
+// definitions take precedence, and every definition takes precedence over a
+// value lookup finds in the host environment. Values are masked with
+// MaskValue so the result is safe to print.
+func Explain(defs []Variable, lookup func(string) (string, bool)) []Explanation {
+	byKey := make(map[string]*Explanation, len(defs))
+	var order []string
+	for _, d := range defs {
+		e, seen := byKey[d.Key]
+		if !seen {
+			e = &Explanation{Key: d.Key, Overrides: []string{}}
+			if lookup != nil {
+				if _, ok := lookup(d.Key); ok {
+					e.Overrides = append(e.Overrides, HostSource)
+				}
+			}
+			byKey[d.Key] = e
+			order = append(order, d.Key)
+		} else {
+			e.Overrides = append(e.Overrides, fmt.Sprintf("%s:%d", e.Source, e.Line))
+		}
+		e.Value = MaskValue(d.Value)
+		e.Source = d.Source
+		e.Line = d.Line
+	}
+
+	explanations := make([]Explanation, 0, len(order))
+	for _, key := range order {
+		explanations = append(explanations, *byKey[key])
+	}
+	return explanations
+}
@@ -0,0 +1,36 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	var defs []Variable
+	for _, layer := range []struct{ source, content string }{
+		{".env", "HOST=localhost\nPORT=3000\nHOME=/app"},
+		{".env.local", "HOST=127.0.0.1\nEMPTY="},
+		{".env.production", "HOST=prod.example.com\nHOST=db.example.com"},
+	} {
+		parsed, err := Parse(layer.content)
+		assert.NoError(t, err)
+		for i := range parsed {
+			parsed[i].Source = layer.source
+		}
+		defs = append(defs, parsed...)
+	}
+
+	host := map[string]string{"HOME": "/home/me"}
+	explanations := Explain(defs, func(key string) (string, bool) {
+		value, ok := host[key]
+		return value, ok
+	})
+
+	assert.Equal(t, []Explanation{
+		{Key: "HOST", Value: "********", Source: ".env.production", Line: 2, Overrides: []string{".env:1", ".env.local:1", ".env.production:1"}},
+		{Key: "PORT", Value: "********", Source: ".env", Line: 2, Overrides: []string{}},
+		{Key: "HOME", Value: "********", Source: ".env", Line: 3, Overrides: []string{HostSource}},
+		{Key: "EMPTY", Value: "", Source: ".env.local", Line: 2, Overrides: []string{}},
+	}, explanations)
+}
@@ -0,0 +1,221 @@
+package env
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats supported by Render.
+const (
+	FormatJSON          = "json"
+	FormatYAML          = "yaml"
+	FormatShell         = "shell"
+	FormatDockerEnv     = "docker-env"
+	FormatK8sSecret     = "k8s-secret"
+	FormatK8sConfigMap  = "k8s-configmap"
+	FormatSystemd       = "systemd"
+	FormatGitHubActions = "github-actions"
+)
+
+// ExportFormats lists every format Render accepts.
+var ExportFormats = []string{
+	FormatJSON,
+	FormatYAML,
+	FormatShell,
+	FormatDockerEnv,
+	FormatK8sSecret,
+	FormatK8sConfigMap,
+	FormatSystemd,
+	FormatGitHubActions,
+}
+
+var (
+	shellNameRegex       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	k8sResourceNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+)
+
+// Render formats effective variables for another tool. name is the resource
+// name used by the Kubernetes formats and is ignored by the others.
+func Render(format string, vars []Variable, name string) (string, error) {
+	switch format {
+	case FormatJSON:
+		return renderJSON(vars)
+	case FormatYAML:
+		return renderYAML(mappingNode(vars, identity))
+	case FormatShell:
+		return renderShell(vars)
+	case FormatDockerEnv:
+		return renderDockerEnv(vars)
+	case FormatK8sSecret:
+		return renderK8s("Secret", name, vars)
+	case FormatK8sConfigMap:
+		return renderK8s("ConfigMap", name, vars)
+	case FormatSystemd:
+		return renderSystemd(vars), nil
+	case FormatGitHubActions:
+		return renderGitHubActions(vars)
+	default:
+		return "", fmt.Errorf("unsupported format %q, expected one of: %s", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+func identity(value string) string {
+	return value
+}
+
+func renderJSON(vars []Variable) (string, error) {
+	// Built by hand to keep keys in file order.
+	var b strings.Builder
+	b.WriteString("{")
+	for i, v := range vars {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := marshalJSONString(v.Key)
+		if err != nil {
+			return "", err
+		}
+		value, err := marshalJSONString(v.Value)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "\n  %s: %s", key, value)
+	}
+	if len(vars) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// marshalJSONString encodes s without the HTML escaping json.Marshal applies,
+// so values such as URLs stay readable.
+func marshalJSONString(s string) (string, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func mappingNode(vars []Variable, transform func(string) string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, v := range vars {
+		node.Content = append(node.Content, scalarNode(v.Key), scalarNode(transform(v.Value)))
+	}
+	return node
+}
+
+func renderYAML(node *yaml.Node) (string, error) {
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func renderK8s(kind, name string, vars []Variable) (string, error) {
+	if !k8sResourceNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid Kubernetes resource name %q: use lowercase letters, digits, '-' and '.'", name)
+	}
+
+	data := mappingNode(vars, identity)
+	if kind == "Secret" {
+		data = mappingNode(vars, func(value string) string {
+			return base64.StdEncoding.EncodeToString([]byte(value))
+		})
+	}
+
+	metadata := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	metadata.Content = append(metadata.Content, scalarNode("name"), scalarNode(name))
+
+	doc := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	doc.Content = append(doc.Content,
+		scalarNode("apiVersion"), scalarNode("v1"),
+		scalarNode("kind"), scalarNode(kind),
+		scalarNode("metadata"), metadata,
+	)
+	if kind == "Secret" {
+		doc.Content = append(doc.Content, scalarNode("type"), scalarNode("Opaque"))
+	}
+	if len(vars) > 0 {
+		doc.Content = append(doc.Content, scalarNode("data"), data)
+	}
+	return renderYAML(doc)
+}
+
+func renderShell(vars []Variable) (string, error) {
+	var b strings.Builder
+	for _, v := range vars {
+		if !shellNameRegex.MatchString(v.Key) {
+			return "", fmt.Errorf("%s is not a valid shell variable name", v.Key)
+		}
+		fmt.Fprintf(&b, "export %s='%s'\n", v.Key, strings.ReplaceAll(v.Value, "'", `'\''`))
+	}
+	return b.String(), nil
+}
+
+// renderDockerEnv writes the format read by `docker run --env-file`, which
+// takes values literally and has no quoting or multi-line support.
+func renderDockerEnv(vars []Variable) (string, error) {
+	var b strings.Builder
+	for _, v := range vars {
+		if strings.ContainsAny(v.Value, "\r\n") {
+			return "", fmt.Errorf("%s spans multiple lines, which docker env files can't represent", v.Key)
+		}
+		fmt.Fprintf(&b, "%s=%s\n", v.Key, v.Value)
+	}
+	return b.String(), nil
+}
+
+// renderSystemd writes a unit drop-in setting each variable with
+// Environment=. '%' is doubled so systemd doesn't treat it as a specifier.
+func renderSystemd(vars []Variable) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "%", "%%")
+
+	var b strings.Builder
+	b.WriteString("[Service]\n")
+	for _, v := range vars {
+		fmt.Fprintf(&b, "Environment=\"%s=%s\"\n", v.Key, replacer.Replace(v.Value))
+	}
+	return b.String()
+}
+
+// renderGitHubActions writes lines for the $GITHUB_ENV file. Multi-line
+// values use the heredoc syntax with a random delimiter.
+func renderGitHubActions(vars []Variable) (string, error) {
+	var b strings.Builder
+	for _, v := range vars {
+		if !strings.ContainsAny(v.Value, "\r\n") {
+			fmt.Fprintf(&b, "%s=%s\n", v.Key, v.Value)
+			continue
+		}
+
+		random := make([]byte, 8)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		delimiter := "HX_EOF_" + hex.EncodeToString(random)
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", v.Key, delimiter, v.Value, delimiter)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,105 @@
+package env
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func renderContent(t *testing.T, format, content, name string) (string, error) {
+	t.Helper()
+	defs, err := Parse(content)
+	assert.NoError(t, err)
+	return Render(format, Effective(defs), name)
+}
+
+func TestRender(t *testing.T) {
+	content := "B=2\nA=its\nB=true\nURL=http://x?a=1&b=%20\n"
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   FormatJSON,
+			expected: "{\n  \"B\": \"true\",\n  \"A\": \"its\",\n  \"URL\": \"http://x?a=1&b=%20\"\n}\n",
+		},
+		{
+			format:   FormatYAML,
+			expected: "B: \"true\"\nA: its\nURL: http://x?a=1&b=%20\n",
+		},
+		{
+			format:   FormatShell,
+			expected: "export B='true'\nexport A='its'\nexport URL='http://x?a=1&b=%20'\n",
+		},
+		{
+			format:   FormatDockerEnv,
+			expected: "B=true\nA=its\nURL=http://x?a=1&b=%20\n",
+		},
+		{
+			format:   FormatK8sSecret,
+			expected: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app-production\ntype: Opaque\ndata:\n  B: dHJ1ZQ==\n  A: aXRz\n  URL: aHR0cDovL3g/YT0xJmI9JTIw\n",
+		},
+		{
+			format:   FormatK8sConfigMap,
+			expected: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-production\ndata:\n  B: \"true\"\n  A: its\n  URL: http://x?a=1&b=%20\n",
+		},
+		{
+			format:   FormatSystemd,
+			expected: "[Service]\nEnvironment=\"B=true\"\nEnvironment=\"A=its\"\nEnvironment=\"URL=http://x?a=1&b=%%20\"\n",
+		},
+		{
+			format:   FormatGitHubActions,
+			expected: "B=true\nA=its\nURL=http://x?a=1&b=%20\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, err := renderContent(t, tt.format, content, "app-production")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, out)
+		})
+	}
+}
+
+func TestRenderEscaping(t *testing.T) {
+	t.Run("shell_quotes", func(t *testing.T) {
+		out, err := renderContent(t, FormatShell, `A="it's $HOME"`, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "export A='it'\\''s $HOME'\n", out)
+	})
+
+	t.Run("systemd_multi_line", func(t *testing.T) {
+		out, err := renderContent(t, FormatSystemd, `A="a \"b\"\nc"`, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "[Service]\nEnvironment=\"A=a \\\"b\\\"\\nc\"\n", out)
+	})
+
+	t.Run("github_actions_multi_line", func(t *testing.T) {
+		out, err := renderContent(t, FormatGitHubActions, "CERT=\"line1\nline2\"", "")
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^CERT<<(HX_EOF_[0-9a-f]{16})\nline1\nline2\n(HX_EOF_[0-9a-f]{16})\n$`), out)
+	})
+
+	t.Run("empty_json", func(t *testing.T) {
+		out, err := renderContent(t, FormatJSON, "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "{}\n", out)
+	})
+}
+
+func TestRenderErrors(t *testing.T) {
+	_, err := renderContent(t, FormatDockerEnv, "A=\"1\n2\"", "")
+	assert.ErrorContains(t, err, "A spans multiple lines")
+
+	_, err = renderContent(t, FormatShell, "A.B=1", "")
+	assert.ErrorContains(t, err, "A.B is not a valid shell variable name")
+
+	_, err = renderContent(t, FormatK8sSecret, "A=1", "Not_Valid")
+	assert.ErrorContains(t, err, "invalid Kubernetes resource name")
+
+	_, err = renderContent(t, "toml", "A=1", "")
+	assert.ErrorContains(t, err, `unsupported format "toml"`)
+}
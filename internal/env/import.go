@@ -0,0 +1,219 @@
+package env
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats accepted by Convert in addition to FormatJSON, FormatYAML,
+// FormatK8sSecret and FormatK8sConfigMap.
+const (
+	// FormatCompose is a docker compose env_file, which uses dotenv syntax.
+	FormatCompose = "compose"
+)
+
+// ImportFormats lists every format Convert accepts.
+var ImportFormats = []string{
+	FormatJSON,
+	FormatYAML,
+	FormatCompose,
+	FormatK8sSecret,
+	FormatK8sConfigMap,
+}
+
+// Convert reads variables from another tool's format. JSON and YAML must be
+// a flat object of scalar values; Kubernetes manifests contribute their data
+// (base64-decoded for Secrets) and stringData. Keys keep their source order
+// and must be valid variable names.
+func Convert(format string, data []byte) ([]Variable, error) {
+	var vars []Variable
+	var err error
+	switch format {
+	case FormatJSON:
+		vars, err = convertJSON(data)
+	case FormatYAML:
+		vars, err = convertMapping(data)
+	case FormatCompose:
+		var defs []Variable
+		defs, err = Parse(string(data))
+		vars = Effective(defs)
+	case FormatK8sSecret:
+		vars, err = convertK8s(data, "Secret")
+	case FormatK8sConfigMap:
+		vars, err = convertK8s(data, "ConfigMap")
+	default:
+		return nil, fmt.Errorf("unsupported format %q, expected one of: %s", format, strings.Join(ImportFormats, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range vars {
+		if err := ValidateKey(v.Key); err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
+// Serialize renders variables as dotenv content, one KEY=VALUE line each.
+func Serialize(vars []Variable) string {
+	var b strings.Builder
+	for _, v := range vars {
+		fmt.Fprintf(&b, "%s=%s\n", v.Key, FormatValue(v.Value))
+	}
+	return b.String()
+}
+
+// convertJSON reads a flat JSON object token by token to keep its key order.
+func convertJSON(data []byte) ([]Variable, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("document must be an object of KEY: value pairs")
+	}
+
+	var defs []Variable
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		v := Variable{Key: key}
+		switch value := value.(type) {
+		case string:
+			v.Value = value
+		case json.Number:
+			v.Value = value.String()
+		case bool:
+			v.Value = fmt.Sprint(value)
+		case nil:
+		default:
+			return nil, fmt.Errorf("document has a nested value for %s; only flat values can be imported", key)
+		}
+		defs = append(defs, v)
+	}
+	return Effective(defs), nil
+}
+
+func convertMapping(data []byte) ([]Variable, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return mappingVariables(doc.Content[0], "document", decodeNone)
+}
+
+// mappingVariables reads the scalar key/value pairs of a YAML mapping node,
+// keeping the last value of duplicate keys.
+func mappingVariables(node *yaml.Node, what string, decode func(string) (string, error)) ([]Variable, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s must be an object of KEY: value pairs", what)
+	}
+
+	var defs []Variable
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s has a non-scalar key on line %d", what, key.Line)
+		}
+		if value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s has a nested value for %s on line %d; only flat values can be imported", what, key.Value, value.Line)
+		}
+
+		v := value.Value
+		if value.Tag == "!!null" {
+			v = ""
+		}
+		decoded, err := decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key.Value, err)
+		}
+		defs = append(defs, Variable{Key: key.Value, Value: decoded, Line: key.Line})
+	}
+	return Effective(defs), nil
+}
+
+// convertK8s reads the first manifest of the given kind from a possibly
+// multi-document YAML stream.
+func convertK8s(data []byte, kind string) ([]Variable, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("no Kubernetes %s found", kind)
+			}
+			return nil, err
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+
+		manifest := doc.Content[0]
+		if field(manifest, "kind") == nil || field(manifest, "kind").Value != kind {
+			continue
+		}
+
+		var vars []Variable
+		if dataNode := field(manifest, "data"); dataNode != nil && dataNode.Tag != "!!null" {
+			decode := decodeNone
+			if kind == "Secret" {
+				decode = decodeBase64
+			}
+			dataVars, err := mappingVariables(dataNode, kind+" data", decode)
+			if err != nil {
+				return nil, err
+			}
+			vars = append(vars, dataVars...)
+		}
+		if stringData := field(manifest, "stringData"); stringData != nil && kind == "Secret" && stringData.Tag != "!!null" {
+			stringVars, err := mappingVariables(stringData, kind+" stringData", decodeNone)
+			if err != nil {
+				return nil, err
+			}
+			// stringData takes precedence over data, as in the API server.
+			vars = append(vars, stringVars...)
+		}
+		return Effective(vars), nil
+	}
+}
+
+// field returns the value of key in a YAML mapping node, or nil.
+func field(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func decodeNone(value string) (string, error) {
+	return value, nil
+}
+
+func decodeBase64(value string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("not valid base64")
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,104 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		data     string
+		expected []Variable
+	}{
+		{
+			name:   "json",
+			format: FormatJSON,
+			data:   `{"B": "two", "A": 1, "ON": true, "NONE": null, "URL": "http:\/\/x\n"}`,
+			expected: []Variable{
+				{Key: "B", Value: "two"},
+				{Key: "A", Value: "1"},
+				{Key: "ON", Value: "true"},
+				{Key: "NONE", Value: ""},
+				{Key: "URL", Value: "http://x\n"},
+			},
+		},
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			data:   "B: two\nA: 1.50\nCERT: |\n  line1\n  line2\n",
+			expected: []Variable{
+				{Key: "B", Value: "two", Line: 1},
+				{Key: "A", Value: "1.50", Line: 2},
+				{Key: "CERT", Value: "line1\nline2\n", Line: 3},
+			},
+		},
+		{
+			name:   "compose",
+			format: FormatCompose,
+			data:   "# app\nA=1\nB=\"x y\"\nA=2\n",
+			expected: []Variable{
+				{Key: "A", Value: "2", Line: 4, endLine: 4},
+				{Key: "B", Value: "x y", Line: 3, Quote: '"', endLine: 3},
+			},
+		},
+		{
+			name:   "k8s_secret",
+			format: FormatK8sSecret,
+			data:   "apiVersion: v1\nkind: ConfigMap\ndata:\n  X: y\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: api\ndata:\n  PASSWORD: aHVudGVyMg==\n  TOKEN: b2xk\nstringData:\n  TOKEN: new\n",
+			expected: []Variable{
+				{Key: "PASSWORD", Value: "hunter2", Line: 11},
+				{Key: "TOKEN", Value: "new", Line: 14},
+			},
+		},
+		{
+			name:   "k8s_configmap",
+			format: FormatK8sConfigMap,
+			data:   "apiVersion: v1\nkind: ConfigMap\ndata:\n  X: y\n",
+			expected: []Variable{
+				{Key: "X", Value: "y", Line: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := Convert(tt.format, []byte(tt.data))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, vars)
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	_, err := Convert(FormatJSON, []byte(`{"A": {"nested": true}}`))
+	assert.ErrorContains(t, err, "nested value for A")
+
+	_, err = Convert(FormatYAML, []byte("- a\n- b\n"))
+	assert.ErrorContains(t, err, "must be an object")
+
+	_, err = Convert(FormatYAML, []byte("my-key: 1\n"))
+	assert.ErrorContains(t, err, "Invalid variable name 'my-key'")
+
+	_, err = Convert(FormatK8sSecret, []byte("kind: Secret\ndata:\n  A: '%%%'\n"))
+	assert.ErrorContains(t, err, "invalid value for A: not valid base64")
+
+	_, err = Convert(FormatK8sSecret, []byte("kind: ConfigMap\n"))
+	assert.ErrorContains(t, err, "no Kubernetes Secret found")
+
+	_, err = Convert("toml", nil)
+	assert.ErrorContains(t, err, `unsupported format "toml"`)
+}
+
+func TestSerialize(t *testing.T) {
+	vars := []Variable{{Key: "A", Value: "1"}, {Key: "B", Value: "x y\n$HOME"}}
+
+	content := Serialize(vars)
+
+	assert.Equal(t, "A=1\nB=\"x y\\n\\$HOME\"\n", content)
+	parsed, err := Variables(content)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "x y\n$HOME"}, parsed)
+}
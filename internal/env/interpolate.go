@@ -0,0 +1,206 @@
+package env
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var referenceNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+
+// Interpolate expands variable references in layered definitions, where later
+// definitions override earlier ones. It supports `$VAR`, `${VAR}`,
+// `${VAR:-default}` (used when VAR is unset or empty) and `${VAR-default}`
+// (used when VAR is unset).
+//
+// References resolve against the effective definition of each key, falling
+// back to lookup (usually os.LookupEnv) for keys no layer defines, so their
+// order in the files doesn't matter. A definition that references its own key
+// sees the value it overrides, which makes `PATH=${PATH}:/extra` work.
+// Single-quoted values and `\$` inside double quotes are never expanded.
+//
+// It returns a copy of defs in which every effective definition has been
+// expanded, or an error for circular or malformed references.
+func Interpolate(defs []Variable, lookup func(string) (string, bool)) ([]Variable, error) {
+	in := &interpolator{
+		defs:     defs,
+		last:     make(map[string]int, len(defs)),
+		lookup:   lookup,
+		resolved: make(map[int]string),
+	}
+	for i, d := range defs {
+		in.last[d.Key] = i
+	}
+
+	out := make([]Variable, len(defs))
+	copy(out, defs)
+	for i, d := range defs {
+		if in.last[d.Key] != i {
+			continue
+		}
+		value, err := in.resolveDef(i)
+		if err != nil {
+			return nil, err
+		}
+		out[i].Value = value
+	}
+	return out, nil
+}
+
+type interpolator struct {
+	defs     []Variable
+	last     map[string]int
+	lookup   func(string) (string, bool)
+	resolved map[int]string
+	stack    []int
+}
+
+func (in *interpolator) resolveDef(i int) (string, error) {
+	if value, ok := in.resolved[i]; ok {
+		return value, nil
+	}
+
+	for n, j := range in.stack {
+		if j == i {
+			var path []string
+			for _, k := range in.stack[n:] {
+				path = append(path, in.defs[k].Key)
+			}
+			path = append(path, in.defs[i].Key)
+			return "", fmt.Errorf("circular variable reference: %s", strings.Join(path, " -> "))
+		}
+	}
+
+	d := in.defs[i]
+	if d.Quote == '\'' {
+		in.resolved[i] = d.Value
+		return d.Value, nil
+	}
+
+	in.stack = append(in.stack, i)
+	value, err := in.expand(d.Value, d.escapedDollars, i)
+	in.stack = in.stack[:len(in.stack)-1]
+	if err != nil {
+		return "", err
+	}
+
+	in.resolved[i] = value
+	return value, nil
+}
+
+// lookupRef resolves a reference to name made from definition from.
+func (in *interpolator) lookupRef(name string, from int) (string, bool, error) {
+	idx := -1
+	if name == in.defs[from].Key {
+		for j := from - 1; j >= 0; j-- {
+			if in.defs[j].Key == name {
+				idx = j
+				break
+			}
+		}
+	} else if j, ok := in.last[name]; ok {
+		idx = j
+	}
+
+	if idx >= 0 {
+		value, err := in.resolveDef(idx)
+		return value, true, err
+	}
+
+	if in.lookup == nil {
+		return "", false, nil
+	}
+	value, ok := in.lookup(name)
+	return value, ok, nil
+}
+
+func (in *interpolator) expand(s string, escapedDollars []int, from int) (string, error) {
+	escaped := make(map[int]bool, len(escapedDollars))
+	for _, offset := range escapedDollars {
+		escaped[offset] = true
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || escaped[i] || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := matchingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference in %s: %q", in.defs[from].Key, s[i:])
+			}
+			value, err := in.expandBraced(s[i+2:end], from)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+			continue
+		}
+
+		name := referenceNameRegex.FindString(s[i+1:])
+		if name == "" {
+			b.WriteByte('$')
+			continue
+		}
+		value, _, err := in.lookupRef(name, from)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		i += len(name)
+	}
+	return b.String(), nil
+}
+
+// expandBraced expands the inside of a `${...}` reference.
+func (in *interpolator) expandBraced(inner string, from int) (string, error) {
+	name := referenceNameRegex.FindString(inner)
+	if name == "" {
+		return "", fmt.Errorf("invalid reference in %s: ${%s}", in.defs[from].Key, inner)
+	}
+
+	value, ok, err := in.lookupRef(name, from)
+	if err != nil {
+		return "", err
+	}
+
+	rest := inner[len(name):]
+	switch {
+	case rest == "":
+		return value, nil
+	case strings.HasPrefix(rest, ":-"):
+		if ok && value != "" {
+			return value, nil
+		}
+		return in.expand(rest[2:], nil, from)
+	case strings.HasPrefix(rest, "-"):
+		if ok {
+			return value, nil
+		}
+		return in.expand(rest[1:], nil, from)
+	default:
+		return "", fmt.Errorf("invalid reference in %s: ${%s}", in.defs[from].Key, inner)
+	}
+}
+
+// matchingBrace returns the index of the '}' closing a reference whose body
+// starts at start, accounting for nested references in defaults.
+func matchingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,115 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func interpolateContent(t *testing.T, host map[string]string, layers ...string) ([]string, error) {
+	t.Helper()
+	var defs []Variable
+	for _, layer := range layers {
+		parsed, err := Parse(layer)
+		assert.NoError(t, err)
+		defs = append(defs, parsed...)
+	}
+
+	resolved, err := Interpolate(defs, func(key string) (string, bool) {
+		value, ok := host[key]
+		return value, ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	return EffectiveEnviron(resolved), nil
+}
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     map[string]string
+		layers   []string
+		expected []string
+	}{
+		{
+			name:     "braced_and_bare_references",
+			layers:   []string{"USER=app\nPASS=secret\nURL=postgres://${USER}:$PASS@db/main"},
+			expected: []string{"USER=app", "PASS=secret", "URL=postgres://app:secret@db/main"},
+		},
+		{
+			name:     "references_defined_later",
+			layers:   []string{"URL=http://${HOST}\nHOST=example.com"},
+			expected: []string{"URL=http://example.com", "HOST=example.com"},
+		},
+		{
+			name:     "references_across_layers_use_effective_value",
+			layers:   []string{"HOST=localhost\nURL=http://${HOST}", "HOST=prod.example.com"},
+			expected: []string{"HOST=prod.example.com", "URL=http://prod.example.com"},
+		},
+		{
+			name:     "falls_back_to_host_environment",
+			host:     map[string]string{"HOME": "/home/me"},
+			layers:   []string{"CACHE=${HOME}/.cache"},
+			expected: []string{"CACHE=/home/me/.cache"},
+		},
+		{
+			name:     "file_values_win_over_host",
+			host:     map[string]string{"HOST": "host-value"},
+			layers:   []string{"HOST=file-value\nURL=${HOST}"},
+			expected: []string{"HOST=file-value", "URL=file-value"},
+		},
+		{
+			name:     "defaults",
+			host:     map[string]string{"EMPTY": ""},
+			layers:   []string{"A=${MISSING:-fallback}\nB=${EMPTY:-fallback}\nC=${EMPTY-fallback}\nD=${MISSING-fallback}\nE=${MISSING:-${OTHER:-nested}}"},
+			expected: []string{"A=fallback", "B=fallback", "C=", "D=fallback", "E=nested"},
+		},
+		{
+			name:     "unset_without_default_is_empty",
+			layers:   []string{"A=x${MISSING}y"},
+			expected: []string{"A=xy"},
+		},
+		{
+			name:     "self_reference_sees_overridden_value",
+			host:     map[string]string{"PATH": "/usr/bin"},
+			layers:   []string{"PATH=${PATH}:/opt/a", "PATH=${PATH}:/opt/b"},
+			expected: []string{"PATH=/usr/bin:/opt/a:/opt/b"},
+		},
+		{
+			name:     "single_quotes_and_escapes_are_literal",
+			layers:   []string{"A=1\nB='${A}'\nC=\"\\${A} ${A}\""},
+			expected: []string{"A=1", "B=${A}", "C=${A} 1"},
+		},
+		{
+			name:     "lone_dollar_is_literal",
+			layers:   []string{"PRICE=5$\nOTHER=$ 1"},
+			expected: []string{"PRICE=5$", "OTHER=$ 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			environ, err := interpolateContent(t, tt.host, tt.layers...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, environ)
+		})
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	t.Run("detects_cycles", func(t *testing.T) {
+		_, err := interpolateContent(t, nil, "A=${B}\nB=${C}\nC=${A}")
+		assert.ErrorContains(t, err, "circular variable reference: A -> B -> C -> A")
+	})
+
+	t.Run("detects_unterminated_reference", func(t *testing.T) {
+		_, err := interpolateContent(t, nil, "A=${B")
+		assert.ErrorContains(t, err, "unterminated reference")
+	})
+
+	t.Run("detects_invalid_reference", func(t *testing.T) {
+		_, err := interpolateContent(t, nil, "A=${B?oops}")
+		assert.ErrorContains(t, err, "invalid reference")
+	})
+}
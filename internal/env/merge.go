@@ -0,0 +1,244 @@
+package env
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	conflictStartMarker = "<<<<<<<"
+	conflictSepMarker   = "======="
+	conflictEndMarker   = ">>>>>>>"
+)
+
+// MergeConflict is a key that was changed differently on both sides of a
+// merge. Local and Remote are nil when that side deleted the key.
+type MergeConflict struct {
+	Key    string
+	Base   *string
+	Local  *string
+	Remote *string
+}
+
+// Resolution is how a merge conflict was settled.
+type Resolution int
+
+const (
+	// ResolveWithMarkers leaves the conflict in the content between markers.
+	ResolveWithMarkers Resolution = iota
+	ResolveLocal
+	ResolveRemote
+)
+
+// ConflictResolver decides how to settle a merge conflict.
+type ConflictResolver func(MergeConflict) (Resolution, error)
+
+// MergeResult is the outcome of a three-way merge.
+type MergeResult struct {
+	Content string
+	// Applied lists the keys whose remote change was merged into the content.
+	Applied []string
+	// Conflicts lists the conflicts left in the content between markers.
+	Conflicts []MergeConflict
+}
+
+// Merge performs a key-level three-way merge of dotenv content. Changes made
+// on only one side since base are kept; keys changed differently on both sides
+// are passed to resolve, or written between git-style conflict markers when
+// resolve is nil or leaves them unresolved. The local content's layout and
+// comments are preserved and remoteLabel annotates the remote side of each
+// conflict.
+func Merge(base, local, remote, remoteLabel string, resolve ConflictResolver) (MergeResult, error) {
+	baseVars, err := Variables(base)
+	if err != nil {
+		return MergeResult{}, fmt.Errorf("base version: %w", err)
+	}
+	localDefs, err := Parse(local)
+	if err != nil {
+		return MergeResult{}, fmt.Errorf("local version: %w", err)
+	}
+	remoteDefs, err := Parse(remote)
+	if err != nil {
+		return MergeResult{}, fmt.Errorf("remote version: %w", err)
+	}
+	localVars, remoteVars := effectiveDefinitions(localDefs), effectiveDefinitions(remoteDefs)
+
+	var keys []string
+	for _, v := range append(Effective(localDefs), Effective(remoteDefs)...) {
+		keys = append(keys, v.Key)
+	}
+
+	result := MergeResult{Content: local}
+	var blocks []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		b, l, r := lookupValue(baseVars, key), definedValue(localVars, key), definedValue(remoteVars, key)
+		take := ResolveLocal
+		switch {
+		case equalValues(l, r), equalValues(r, b):
+		case equalValues(l, b):
+			take = ResolveRemote
+		default:
+			conflict := MergeConflict{Key: key, Base: b, Local: l, Remote: r}
+			take = ResolveWithMarkers
+			if resolve != nil {
+				if take, err = resolve(conflict); err != nil {
+					return MergeResult{}, err
+				}
+			}
+			if take == ResolveWithMarkers {
+				result.Conflicts = append(result.Conflicts, conflict)
+				blocks = append(blocks, conflictBlock(key, local, localVars, remote, remoteVars, remoteLabel))
+				continue
+			}
+		}
+
+		if take != ResolveRemote || equalValues(l, r) {
+			continue
+		}
+		if r == nil {
+			result.Content, _, err = UnsetVariable(result.Content, key)
+		} else {
+			// Copy the remote definition verbatim so quoting and references
+			// keep their meaning.
+			result.Content, err = setDefinition(result.Content, key, definitionText(remote, remoteVars[key]))
+		}
+		if err != nil {
+			return MergeResult{}, err
+		}
+		result.Applied = append(result.Applied, key)
+	}
+
+	if len(result.Conflicts) > 0 {
+		result.Content, err = writeConflicts(result.Content, result.Conflicts, blocks)
+		if err != nil {
+			return MergeResult{}, err
+		}
+	}
+	sort.Strings(result.Applied)
+
+	return result, nil
+}
+
+// ConflictMarkerLine returns the line of the first merge conflict marker Merge
+// left in content, if there is one.
+func ConflictMarkerLine(content string) (int, bool) {
+	for i, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if isConflictMarker(strings.TrimSpace(line)) {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func isConflictMarker(line string) bool {
+	return strings.HasPrefix(line, conflictStartMarker) ||
+		strings.HasPrefix(line, conflictSepMarker) ||
+		strings.HasPrefix(line, conflictEndMarker)
+}
+
+// writeConflicts replaces the local definitions of each conflicting key with
+// a conflict block; keys the local side deleted are appended.
+func writeConflicts(content string, conflicts []MergeConflict, conflictBlocks []string) (string, error) {
+	parsed, err := Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	blocks := make(map[string]string, len(conflicts))
+	for i, c := range conflicts {
+		blocks[c.Key] = conflictBlocks[i]
+	}
+
+	lines := strings.Split(content, "\n")
+	replace := make(map[int]string)
+	placed := make(map[string]bool)
+	drop := make(map[int]bool)
+	for _, v := range parsed {
+		block, ok := blocks[v.Key]
+		if !ok {
+			continue
+		}
+		if !placed[v.Key] {
+			replace[v.Line-1] = block
+			placed[v.Key] = true
+		}
+		for line := v.Line; line <= v.endLine; line++ {
+			drop[line-1] = true
+		}
+	}
+
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if block, ok := replace[i]; ok {
+			out = append(out, block)
+			continue
+		}
+		if drop[i] {
+			continue
+		}
+		out = append(out, line)
+	}
+	merged := strings.Join(out, "\n")
+
+	for _, c := range conflicts {
+		if c.Local != nil {
+			continue
+		}
+		if merged != "" && !strings.HasSuffix(merged, "\n") {
+			merged += "\n"
+		}
+		merged += blocks[c.Key] + "\n"
+	}
+	return merged, nil
+}
+
+// conflictBlock renders both sides of a conflict on key between markers,
+// copying each side's definition verbatim.
+func conflictBlock(key, local string, localVars map[string]Variable, remote string, remoteVars map[string]Variable, remoteLabel string) string {
+	lines := []string{conflictStartMarker + " local"}
+	if v, ok := localVars[key]; ok {
+		lines = append(lines, definitionText(local, v))
+	}
+	lines = append(lines, conflictSepMarker)
+	if v, ok := remoteVars[key]; ok {
+		lines = append(lines, definitionText(remote, v))
+	}
+	lines = append(lines, conflictEndMarker+" "+remoteLabel)
+	return strings.Join(lines, "\n")
+}
+
+func effectiveDefinitions(defs []Variable) map[string]Variable {
+	vars := make(map[string]Variable, len(defs))
+	for _, v := range defs {
+		vars[v.Key] = v
+	}
+	return vars
+}
+
+func definedValue(vars map[string]Variable, key string) *string {
+	if v, ok := vars[key]; ok {
+		return &v.Value
+	}
+	return nil
+}
+
+func lookupValue(vars map[string]string, key string) *string {
+	if value, ok := vars[key]; ok {
+		return &value
+	}
+	return nil
+}
+
+func equalValues(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
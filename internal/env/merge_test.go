@@ -0,0 +1,80 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	t.Run("combines_changes_from_both_sides", func(t *testing.T) {
+		base := "A=1\nB=2\nC=3\n"
+		local := "# mine\nA=1\nB=local\nC=3\nLOCAL_NEW=x\n"
+		remote := "A=remote\nB=2\nREMOTE_NEW=y\n"
+
+		result, err := Merge(base, local, remote, "remote v4", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "# mine\nA=remote\nB=local\nLOCAL_NEW=x\nREMOTE_NEW=y\n", result.Content)
+		assert.Equal(t, []string{"A", "C", "REMOTE_NEW"}, result.Applied)
+		assert.Empty(t, result.Conflicts)
+	})
+
+	t.Run("same_change_on_both_sides_is_not_a_conflict", func(t *testing.T) {
+		result, err := Merge("A=1\n", "A=2\n", "A=2\n", "remote v2", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "A=2\n", result.Content)
+		assert.Empty(t, result.Applied)
+		assert.Empty(t, result.Conflicts)
+	})
+
+	t.Run("copies_remote_definitions_verbatim", func(t *testing.T) {
+		result, err := Merge("URL=a\n", "URL=a\n", "URL=http://${HOST}/x\n", "remote v2", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "URL=http://${HOST}/x\n", result.Content)
+	})
+
+	t.Run("writes_conflict_markers", func(t *testing.T) {
+		base := "A=1\nB=2\nC=3\n"
+		local := "A=local\nB=2\nC=local\n"
+		remote := "A=remote\nB=remote\n"
+
+		result, err := Merge(base, local, remote, "remote v4", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "<<<<<<< local\nA=local\n=======\nA=remote\n>>>>>>> remote v4\nB=remote\n<<<<<<< local\nC=local\n=======\n>>>>>>> remote v4\n", result.Content)
+		assert.Equal(t, []string{"A", "C"}, []string{result.Conflicts[0].Key, result.Conflicts[1].Key})
+		assert.Nil(t, result.Conflicts[1].Remote)
+
+		_, err = Parse(result.Content)
+		assert.ErrorContains(t, err, "unresolved merge conflict")
+	})
+
+	t.Run("appends_conflicts_for_keys_deleted_locally", func(t *testing.T) {
+		result, err := Merge("A=1\nB=1\n", "B=1", "A=2\nB=1\n", "remote v2", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "B=1\n<<<<<<< local\n=======\nA=2\n>>>>>>> remote v2\n", result.Content)
+	})
+
+	t.Run("uses_the_resolver", func(t *testing.T) {
+		base := "A=1\nB=1\n"
+		local := "A=local\nB=local\n"
+		remote := "A=remote\nB=remote\n"
+
+		result, err := Merge(base, local, remote, "remote v2", func(c MergeConflict) (Resolution, error) {
+			assert.Equal(t, "1", *c.Base)
+			if c.Key == "A" {
+				return ResolveRemote, nil
+			}
+			return ResolveLocal, nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "A=remote\nB=local\n", result.Content)
+		assert.Equal(t, []string{"A"}, result.Applied)
+		assert.Empty(t, result.Conflicts)
+	})
+}
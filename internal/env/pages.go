@@ -0,0 +1,37 @@
+package env
+
+import "github.com/Hyphen/cli/internal/models"
+
+// listPageSize is the page size ListAllEnvs and ListAllEnvironments request.
+const listPageSize = 100
+
+// ListAllEnvs returns every pushed environment of an app, reading pages until
+// a short one. Any page that fails fails the whole list, so callers never act
+// on a partial one.
+func ListAllEnvs(es EnvServicer, orgId, appId string) ([]models.Env, error) {
+	return listAll(func(page int) ([]models.Env, error) {
+		return es.ListEnvs(orgId, appId, listPageSize, page)
+	})
+}
+
+// ListAllEnvironments returns every environment of a project, reading pages
+// until a short one, like ListAllEnvs.
+func ListAllEnvironments(es EnvServicer, orgId, projectId string) ([]models.Environment, error) {
+	return listAll(func(page int) ([]models.Environment, error) {
+		return es.ListEnvironments(orgId, projectId, listPageSize, page)
+	})
+}
+
+func listAll[T any](list func(page int) ([]T, error)) ([]T, error) {
+	var all []T
+	for page := 1; ; page++ {
+		items, err := list(page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) < listPageSize {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package env
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAllEnvs(t *testing.T) {
+	full := make([]models.Env, listPageSize)
+	last := []models.Env{{}}
+
+	t.Run("reads_until_a_short_page", func(t *testing.T) {
+		mockEnvService := NewMockEnvService()
+		mockEnvService.On("ListEnvs", "org-1", "app-1", listPageSize, 1).Return(full, nil)
+		mockEnvService.On("ListEnvs", "org-1", "app-1", listPageSize, 2).Return(last, nil)
+
+		envs, err := ListAllEnvs(mockEnvService, "org-1", "app-1")
+
+		assert.NoError(t, err)
+		assert.Len(t, envs, listPageSize+1)
+		mockEnvService.AssertExpectations(t)
+	})
+
+	t.Run("a_failed_page_fails_the_list", func(t *testing.T) {
+		mockEnvService := NewMockEnvService()
+		mockEnvService.On("ListEnvs", "org-1", "app-1", listPageSize, 1).Return(full, nil)
+		mockEnvService.On("ListEnvs", "org-1", "app-1", listPageSize, 2).Return([]models.Env{}, fmt.Errorf("timeout"))
+
+		envs, err := ListAllEnvs(mockEnvService, "org-1", "app-1")
+
+		assert.EqualError(t, err, "timeout")
+		assert.Nil(t, envs)
+	})
+}
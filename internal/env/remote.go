@@ -0,0 +1,158 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/internal/secret"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+)
+
+// RemoteEnv is a version of a remote environment with its decrypted content.
+type RemoteEnv struct {
+	models.Env
+	Content string
+}
+
+// Label describes where the content came from, such as
+// "remote production (version 3)".
+func (r RemoteEnv) Label(envName string) string {
+	if r.Version != nil {
+		return fmt.Sprintf("remote %s (version %d)", envName, *r.Version)
+	}
+	return fmt.Sprintf("remote %s", envName)
+}
+
+// FetchRemote fetches a version of a remote environment, the latest when
+// version is nil, and decrypts it with secret. Versions encrypted with an
+// earlier secret key are decrypted with that key from the keyring.
+//
+// An environment other than default that doesn't exist is an error. A
+// version that doesn't exist, or an environment nothing has been pushed to,
+// is reported with an error matching errors.ErrNotFound; for a missing
+// version it lists the recent ones.
+func FetchRemote(es EnvServicer, orgId, projectId, appId, envName string, secret models.Secret, version *int) (RemoteEnv, error) {
+	if envName != "default" {
+		_, exists, err := es.GetEnvironment(orgId, projectId, envName)
+		if err != nil {
+			return RemoteEnv{}, err
+		}
+		if !exists {
+			return RemoteEnv{}, fmt.Errorf("environment %s not found", envName)
+		}
+	}
+
+	e, err := es.GetEnvironmentEnv(orgId, appId, envName, &secret.SecretKeyId, version)
+	if err != nil {
+		if errors.Is(err, errors.ErrNotFound) {
+			if version != nil {
+				return RemoteEnv{}, errors.Wrapf(err, "version %d of environment %s not found%s", *version, envName, recentVersions(es, orgId, appId, envName))
+			}
+			return RemoteEnv{}, errors.Wrapf(err, "nothing has been pushed to the %s environment yet", envName)
+		}
+		return RemoteEnv{}, err
+	}
+
+	content, err := e.DecryptData(secret)
+	if err != nil {
+		return RemoteEnv{}, err
+	}
+	return RemoteEnv{Env: e, Content: content}, nil
+}
+
+// recentVersions describes the latest versions of an environment, for the
+// error about a version that doesn't exist. It is empty when they can't be
+// listed.
+func recentVersions(es EnvServicer, orgId, appId, envName string) string {
+	versions, err := es.ListEnvVersions(orgId, appId, envName, 10, 1)
+	if err != nil {
+		return ""
+	}
+	var available []string
+	for _, v := range versions {
+		if v.Version != nil {
+			available = append(available, fmt.Sprintf("%d", *v.Version))
+		}
+	}
+	if len(available) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(". Recent versions: %s", strings.Join(available, ", "))
+}
+
+// LoadRemote fetches a version of an environment of the current app, the
+// latest when version is nil, and decrypts it with the project's secret key.
+// The plaintext only ever lives in memory.
+func LoadRemote(envName string, version *int) (RemoteEnv, error) {
+	remote, err := NewRemote(NewService())
+	if err != nil {
+		return RemoteEnv{}, err
+	}
+	return remote.Fetch(envName, version)
+}
+
+// Remote fetches versions of the environments of the current app with the
+// project's secret key, for commands that read more than one.
+type Remote struct {
+	envService EnvServicer
+	orgId      string
+	projectId  string
+	appId      string
+	secret     models.Secret
+}
+
+// NewRemote resolves the current organization, project and app, and loads
+// the project's secret key.
+func NewRemote(es EnvServicer) (*Remote, error) {
+	orgId, err := flags.GetOrganizationID()
+	if err != nil {
+		return nil, err
+	}
+
+	projectId, err := flags.GetProjectID()
+	if err != nil {
+		return nil, err
+	}
+
+	appId, err := flags.GetApplicationID()
+	if err != nil {
+		return nil, err
+	}
+
+	secretValue, _, err := secret.LoadSecret(orgId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Remote{
+		envService: es,
+		orgId:      orgId,
+		projectId:  projectId,
+		appId:      appId,
+		secret:     secretValue,
+	}, nil
+}
+
+// Fetch fetches a version of envName, the latest when version is nil, like
+// FetchRemote.
+func (r *Remote) Fetch(envName string, version *int) (RemoteEnv, error) {
+	return FetchRemote(r.envService, r.orgId, r.projectId, r.appId, envName, r.secret, version)
+}
+
+// LoadLocal reads the local file of an environment, returning its content
+// with a label such as "local .env.production".
+func LoadLocal(envName string) (string, string, error) {
+	fileName, err := GetFileName(envName)
+	if err != nil {
+		return "", "", err
+	}
+
+	e, err := New(fileName)
+	if err != nil {
+		return "", "", err
+	}
+
+	return e.Data, fmt.Sprintf("local %s", fileName), nil
+}
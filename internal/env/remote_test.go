@@ -0,0 +1,64 @@
+package env
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchRemote(t *testing.T) {
+	secret := models.NewSecret(base64.StdEncoding.EncodeToString([]byte("test-secret-test-secret-test-secret-test-secret")))
+
+	t.Run("decrypts_the_latest_version", func(t *testing.T) {
+		remote := NewFromContent("A=1\n")
+		v := 4
+		remote.Version = &v
+		var err error
+		remote.Data, err = remote.EncryptData(secret)
+		assert.NoError(t, err)
+
+		mockEnvService := NewMockEnvService()
+		mockEnvService.On("GetEnvironment", "org-1", "project-123", "production").Return(models.Environment{}, true, nil)
+		mockEnvService.On("GetEnvironmentEnv", "org-1", "app-456", "production", &secret.SecretKeyId, (*int)(nil)).Return(remote, nil)
+
+		fetched, err := FetchRemote(mockEnvService, "org-1", "project-123", "app-456", "production", secret, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "A=1\n", fetched.Content)
+		assert.Equal(t, "remote production (version 4)", fetched.Label("production"))
+	})
+
+	t.Run("nothing_pushed_yet", func(t *testing.T) {
+		mockEnvService := NewMockEnvService()
+		mockEnvService.On("GetEnvironmentEnv", "org-1", "app-456", "default", &secret.SecretKeyId, (*int)(nil)).Return(models.Env{}, errors.ErrNotFound)
+
+		_, err := FetchRemote(mockEnvService, "org-1", "project-123", "app-456", "default", secret, nil)
+
+		assert.True(t, errors.Is(err, errors.ErrNotFound))
+		assert.EqualError(t, err, "nothing has been pushed to the default environment yet")
+	})
+
+	t.Run("missing_version_lists_recent_ones", func(t *testing.T) {
+		v, recent := 9, 8
+		mockEnvService := NewMockEnvService()
+		mockEnvService.On("GetEnvironmentEnv", "org-1", "app-456", "default", &secret.SecretKeyId, &v).Return(models.Env{}, errors.ErrNotFound)
+		mockEnvService.On("ListEnvVersions", "org-1", "app-456", "default", 10, 1).Return([]models.Env{{Version: &recent}}, nil)
+
+		_, err := FetchRemote(mockEnvService, "org-1", "project-123", "app-456", "default", secret, &v)
+
+		assert.True(t, errors.Is(err, errors.ErrNotFound))
+		assert.EqualError(t, err, "version 9 of environment default not found. Recent versions: 8")
+	})
+
+	t.Run("missing_environment", func(t *testing.T) {
+		mockEnvService := NewMockEnvService()
+		mockEnvService.On("GetEnvironment", "org-1", "project-123", "staging").Return(models.Environment{}, false, nil)
+
+		_, err := FetchRemote(mockEnvService, "org-1", "project-123", "app-456", "staging", secret, nil)
+
+		assert.EqualError(t, err, "environment staging not found")
+	})
+}
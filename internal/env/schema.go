@@ -0,0 +1,236 @@
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaFileName is the file, next to the .env files, that declares the
+// variables an app expects.
+const SchemaFileName = ".env.schema"
+
+// Types a schema rule can declare.
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeURL    = "url"
+	TypeEnum   = "enum"
+	TypeRegex  = "regex"
+)
+
+// Schema declares the variables an app's environments must satisfy.
+//
+//	variables:
+//	  DATABASE_URL:
+//	    required: true
+//	    type: url
+//	    environments: [production, staging]
+//	  LOG_LEVEL:
+//	    type: enum
+//	    values: [debug, info, warn, error]
+//	    default: info
+type Schema struct {
+	Variables map[string]*SchemaRule `yaml:"variables"`
+}
+
+// SchemaRule describes a single variable. A rule with no Environments applies
+// to every environment.
+type SchemaRule struct {
+	Required     bool     `yaml:"required"`
+	Type         string   `yaml:"type"`
+	Values       []string `yaml:"values"`
+	Pattern      string   `yaml:"pattern"`
+	Default      *string  `yaml:"default"`
+	Environments []string `yaml:"environments"`
+	Description  string   `yaml:"description"`
+
+	pattern *regexp.Regexp
+}
+
+// SchemaViolation is a variable that doesn't satisfy its rule.
+type SchemaViolation struct {
+	Key     string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Key, v.Message)
+}
+
+// LoadSchema reads the schema in path. It returns nil without an error when
+// the file doesn't exist.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	schema, err := ParseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// ParseSchema parses and checks a YAML schema.
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&schema); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	for _, key := range schema.keys() {
+		rule := schema.Variables[key]
+		if rule == nil {
+			rule = &SchemaRule{}
+			schema.Variables[key] = rule
+		}
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if rule.Default != nil {
+			if msg := rule.check(*rule.Default); msg != "" {
+				return nil, fmt.Errorf("%s: default %s", key, msg)
+			}
+		}
+	}
+	return &schema, nil
+}
+
+func (r *SchemaRule) compile() error {
+	if r.Type == "" {
+		r.Type = TypeString
+	}
+
+	switch r.Type {
+	case TypeString, TypeInt, TypeBool, TypeURL:
+	case TypeEnum:
+		if len(r.Values) == 0 {
+			return fmt.Errorf("enum needs a list of values")
+		}
+	case TypeRegex:
+		if r.Pattern == "" {
+			return fmt.Errorf("regex needs a pattern")
+		}
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		r.pattern = pattern
+	default:
+		return fmt.Errorf("unknown type %q, expected one of: %s", r.Type, strings.Join([]string{TypeString, TypeInt, TypeBool, TypeURL, TypeEnum, TypeRegex}, ", "))
+	}
+	return nil
+}
+
+// check returns why value doesn't match the rule's type, or "". The reason
+// never includes the value itself.
+func (r *SchemaRule) check(value string) string {
+	switch r.Type {
+	case TypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "is not an integer"
+		}
+	case TypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "is not a boolean (true or false)"
+		}
+	case TypeURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+			return "is not an absolute URL"
+		}
+	case TypeEnum:
+		if !slices.Contains(r.Values, value) {
+			return fmt.Sprintf("is not one of: %s", strings.Join(r.Values, ", "))
+		}
+	case TypeRegex:
+		if !r.pattern.MatchString(value) {
+			return fmt.Sprintf("does not match %s", r.Pattern)
+		}
+	}
+	return ""
+}
+
+// AppliesTo reports whether the rule is enforced for envName.
+func (r *SchemaRule) AppliesTo(envName string) bool {
+	return len(r.Environments) == 0 || slices.Contains(r.Environments, envName)
+}
+
+// Validate checks the effective variables of envName against the schema. A
+// missing required variable is fine when its rule has a default. Values are
+// never included in the messages, so they're safe to print.
+func (s *Schema) Validate(envName string, vars map[string]string) []SchemaViolation {
+	var violations []SchemaViolation
+	for _, key := range s.keys() {
+		rule := s.Variables[key]
+		if !rule.AppliesTo(envName) {
+			continue
+		}
+
+		value, ok := vars[key]
+		if !ok {
+			if rule.Required && rule.Default == nil {
+				violations = append(violations, SchemaViolation{Key: key, Message: "is required but not set"})
+			}
+			continue
+		}
+		if msg := rule.check(value); msg != "" {
+			violations = append(violations, SchemaViolation{Key: key, Message: msg})
+		}
+	}
+	return violations
+}
+
+// Defaults returns a definition for every variable of envName that has a
+// default in the schema but isn't in vars.
+func (s *Schema) Defaults(envName string, vars map[string]string) []Variable {
+	var defaults []Variable
+	for _, key := range s.keys() {
+		rule := s.Variables[key]
+		if rule.Default == nil || !rule.AppliesTo(envName) {
+			continue
+		}
+		if _, ok := vars[key]; !ok {
+			defaults = append(defaults, Variable{Key: key, Value: *rule.Default, Source: SchemaFileName})
+		}
+	}
+	return defaults
+}
+
+// FormatViolations lists violations one per line, for error messages.
+func FormatViolations(violations []SchemaViolation) string {
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = "  - " + v.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *Schema) keys() []string {
+	keys := make([]string, 0, len(s.Variables))
+	for key := range s.Variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
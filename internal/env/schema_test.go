@@ -0,0 +1,130 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = `
+variables:
+  DATABASE_URL:
+    required: true
+    type: url
+    environments: [production]
+  PORT:
+    required: true
+    type: int
+    default: "3000"
+  DEBUG:
+    type: bool
+  LOG_LEVEL:
+    type: enum
+    values: [debug, info]
+  API_KEY:
+    type: regex
+    pattern: ^sk_[a-z0-9]+$
+  NAME:
+`
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	assert.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		vars := map[string]string{
+			"DATABASE_URL": "postgres://db/main",
+			"DEBUG":        "true",
+			"LOG_LEVEL":    "info",
+			"API_KEY":      "sk_abc123",
+			"NAME":         "anything",
+		}
+		assert.Empty(t, schema.Validate("production", vars))
+	})
+
+	t.Run("violations_never_include_values", func(t *testing.T) {
+		vars := map[string]string{
+			"PORT":      "eighty",
+			"DEBUG":     "maybe",
+			"LOG_LEVEL": "trace",
+			"API_KEY":   "pk_secret",
+		}
+		assert.Equal(t, []SchemaViolation{
+			{Key: "API_KEY", Message: "does not match ^sk_[a-z0-9]+$"},
+			{Key: "DATABASE_URL", Message: "is required but not set"},
+			{Key: "DEBUG", Message: "is not a boolean (true or false)"},
+			{Key: "LOG_LEVEL", Message: "is not one of: debug, info"},
+			{Key: "PORT", Message: "is not an integer"},
+		}, schema.Validate("production", vars))
+	})
+
+	t.Run("rules_scoped_to_other_environments_are_skipped", func(t *testing.T) {
+		assert.Empty(t, schema.Validate("staging", map[string]string{}))
+	})
+
+	t.Run("invalid_url", func(t *testing.T) {
+		violations := schema.Validate("production", map[string]string{"DATABASE_URL": "localhost"})
+		assert.Equal(t, []SchemaViolation{{Key: "DATABASE_URL", Message: "is not an absolute URL"}}, violations)
+	})
+}
+
+func TestSchemaDefaults(t *testing.T) {
+	schema, err := ParseSchema([]byte(`
+variables:
+  PORT:
+    default: "3000"
+  HOST:
+    default: localhost
+  REGION:
+    default: eu
+    environments: [production]
+  NAME:
+`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Variable{
+		{Key: "PORT", Value: "3000", Source: SchemaFileName},
+	}, schema.Defaults("staging", map[string]string{"HOST": "db"}))
+	assert.Equal(t, []Variable{
+		{Key: "REGION", Value: "eu", Source: SchemaFileName},
+	}, schema.Defaults("production", map[string]string{"HOST": "db", "PORT": "80"}))
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown type \"float\"":    "variables:\n  A:\n    type: float\n",
+		"enum needs a list":         "variables:\n  A:\n    type: enum\n",
+		"regex needs a pattern":     "variables:\n  A:\n    type: regex\n",
+		"invalid pattern":           "variables:\n  A:\n    type: regex\n    pattern: '('\n",
+		"default is not an integer": "variables:\n  A:\n    type: int\n    default: x\n",
+		"field requird not found":   "variables:\n  A:\n    requird: true\n",
+		"Invalid variable name":     "variables:\n  my-key: {}\n",
+	}
+
+	for expected, schema := range tests {
+		_, err := ParseSchema([]byte(schema))
+		assert.ErrorContains(t, err, expected)
+	}
+}
+
+func TestLoadSchemaMissingFile(t *testing.T) {
+	schema, err := LoadSchema(filepath.Join(t.TempDir(), SchemaFileName))
+	assert.NoError(t, err)
+	assert.Nil(t, schema)
+}
+
+func TestGetEnvsInDirectorySkipsSchema(t *testing.T) {
+	originalDir, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { assert.NoError(t, os.Chdir(originalDir)) })
+
+	assert.NoError(t, os.WriteFile(".env", nil, 0600))
+	assert.NoError(t, os.WriteFile(SchemaFileName, nil, 0600))
+
+	files, err := GetEnvsInDirectory()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".env"}, files)
+}
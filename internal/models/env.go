@@ -40,25 +40,31 @@ func (e *Env) EncryptData(secret Secret) (string, error) {
 	return encryptData, nil
 }
 
+// DecryptData decrypts the env's data. Data encrypted with an earlier secret
+// key is decrypted with that key when the key resolver knows it.
 func (e *Env) DecryptData(secret Secret) (string, error) {
+	if e.SecretKeyID != nil {
+		secret = secret.resolveKey(*e.SecretKeyID)
+	}
 	decryptedData, err := secret.Decrypt(e.Data)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to decrypt environment data")
+		return "", errors.Wrapf(err, "Failed to decrypt environment data: %s", err)
 	}
 	return decryptedData, nil
 }
 
 func (e *Env) DecryptVarsAndSaveIntoFile(fileName string, secret Secret) (string, error) {
-	file, err := os.Create(fileName)
+	// Decrypt before touching the file so a wrong key never truncates it.
+	decryptedData, err := secret.Decrypt(e.Data)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to create or open file for saving decrypted variables")
+		return "", errors.Wrap(err, "Failed to decrypt environment data")
 	}
-	defer file.Close()
 
-	decryptedData, err := secret.Decrypt(e.Data)
+	file, err := os.Create(fileName)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to decrypt environment data")
+		return "", errors.Wrap(err, "Failed to create or open file for saving decrypted variables")
 	}
+	defer file.Close()
 
 	_, err = file.WriteString(decryptedData)
 	if err != nil {
@@ -1,14 +1,18 @@
 package models
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hkdf"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Hyphen/cli/pkg/errors"
 )
@@ -33,46 +37,186 @@ func (s Secret) HashSHA() (string, error) {
 	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
 }
 
-// Encrypt encrypts a message using AES encryption
+// Encrypted payloads are written as a versioned envelope:
+//
+//	magic "HXE" | version | secret key id (int64, big endian) | salt | nonce | AES-256-GCM ciphertext
+//
+// The AES key is derived from the decoded bytes of the secret key with
+// HKDF-SHA256 using the per-message salt, and everything before the ciphertext is authenticated as additional
+// data, so a wrong key or a tampered payload fails to decrypt instead of
+// producing garbage. Payloads without the magic prefix are legacy AES-CFB.
+const (
+	envelopeVersion = 2
+	envelopeSaltLen = 16
+	envelopeKDFInfo = "hx env envelope v2"
+)
+
+var envelopeMagic = []byte("HXE")
+
+const envelopeHeaderLen = 3 + 1 + 8 + envelopeSaltLen
+
+var (
+	ErrDecryptionFailed    = errors.New("Failed to decrypt data: the secret key is wrong or the data has been tampered with")
+	ErrUnsupportedEnvelope = errors.New("Encrypted data uses an unsupported format version. Please update the Hyphen CLI")
+)
+
+// Encrypt encrypts a message into an authenticated, versioned envelope
 func (s Secret) Encrypt(message string) (string, error) {
-	hashSHA, err := s.HashSHA()
-	if err != nil {
-		return "", err
+	salt := make([]byte, envelopeSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", errors.Wrap(err, "Failed to generate key derivation salt")
 	}
-	key := []byte(hashSHA)[:32] // AES requires a key of 16, 24, or 32 bytes
 
-	block, err := aes.NewCipher(key)
+	gcm, err := s.envelopeCipher(salt)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to create cipher block")
+		return "", err
 	}
 
-	plaintext := []byte(message)
-	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
-	iv := ciphertext[:aes.BlockSize]
+	header := make([]byte, 0, envelopeHeaderLen)
+	header = append(header, envelopeMagic...)
+	header = append(header, envelopeVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(s.SecretKeyId))
+	header = append(header, salt...)
 
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", errors.Wrap(err, "Failed to generate initialization vector")
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "Failed to generate nonce")
 	}
 
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plaintext)
+	out := append(header, nonce...)
+	out = gcm.Seal(out, nonce, []byte(message), header)
+
+	return base64.URLEncoding.EncodeToString(out), nil
+}
+
+// keyResolver finds an earlier secret key of the same project as current by
+// id, so data encrypted before a key rotation still decrypts.
+var keyResolver func(current Secret, secretKeyId int64) (Secret, bool)
+
+// SetKeyResolver installs the lookup Decrypt uses for payloads encrypted with
+// a secret key other than the one it is called on.
+func SetKeyResolver(resolve func(current Secret, secretKeyId int64) (Secret, bool)) {
+	keyResolver = resolve
+}
 
-	return base64.URLEncoding.EncodeToString(ciphertext), nil
+// resolveKey returns the secret for secretKeyId: s itself, or an earlier key
+// found by the key resolver.
+func (s Secret) resolveKey(secretKeyId int64) Secret {
+	if secretKeyId == s.SecretKeyId || keyResolver == nil {
+		return s
+	}
+	if key, ok := keyResolver(s, secretKeyId); ok {
+		return key
+	}
+	return s
 }
 
-// Decrypt decrypts an encrypted message using AES decryption
+// Decrypt decrypts an encrypted message. Envelope payloads are authenticated;
+// legacy AES-CFB payloads are still accepted for data pushed by older CLIs.
+// Envelopes encrypted with an earlier secret key are decrypted with that key
+// when the key resolver knows it.
 func (s Secret) Decrypt(encryptedMessage string) (string, error) {
-	hashSHA, err := s.HashSHA()
+	payload, err := base64.URLEncoding.DecodeString(encryptedMessage)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to decode encrypted message")
+	}
+
+	if !isEnvelope(payload) {
+		return s.decryptLegacy(payload)
+	}
+
+	plaintext, err := s.decryptEnvelope(payload)
 	if err != nil {
+		// The random IV of a legacy payload can start with the envelope
+		// magic too, so one that doesn't open as an envelope gets a second
+		// chance as legacy data.
+		if legacy, legacyErr := s.decryptLegacy(payload); legacyErr == nil {
+			return legacy, nil
+		}
 		return "", err
 	}
-	key := []byte(hashSHA)[:32] // AES requires a key of 16, 24, or 32 bytes
+	return plaintext, nil
+}
+
+func (s Secret) decryptEnvelope(payload []byte) (string, error) {
+	if payload[len(envelopeMagic)] != envelopeVersion {
+		return "", errors.Wrapf(ErrUnsupportedEnvelope, "Encrypted data uses unsupported format version %d. Please update the Hyphen CLI", payload[len(envelopeMagic)])
+	}
 
-	ciphertext, err := base64.URLEncoding.DecodeString(encryptedMessage)
+	keyId := int64(binary.BigEndian.Uint64(payload[len(envelopeMagic)+1:]))
+	s = s.resolveKey(keyId)
+
+	header := payload[:envelopeHeaderLen]
+	salt := header[envelopeHeaderLen-envelopeSaltLen:]
+
+	gcm, err := s.envelopeCipher(salt)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to decode encrypted message")
+		return "", err
+	}
+
+	rest := payload[envelopeHeaderLen:]
+	if len(rest) < gcm.NonceSize()+gcm.Overhead() {
+		return "", errors.New("Ciphertext is too short")
+	}
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		if keyId != s.SecretKeyId {
+			return "", errors.Wrapf(ErrDecryptionFailed, "Failed to decrypt data: it was encrypted with secret key %d but the loaded key is %d", keyId, s.SecretKeyId)
+		}
+		return "", ErrDecryptionFailed
+	}
+
+	return string(plaintext), nil
+}
+
+// EncryptedSecretKeyID returns the secret key id recorded in the header of an
+// envelope payload. It returns false for legacy payloads, which carry no id.
+func EncryptedSecretKeyID(encryptedMessage string) (int64, bool) {
+	payload, err := base64.URLEncoding.DecodeString(encryptedMessage)
+	if err != nil || !isEnvelope(payload) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(payload[len(envelopeMagic)+1:])), true
+}
+
+func isEnvelope(payload []byte) bool {
+	return len(payload) >= envelopeHeaderLen && bytes.HasPrefix(payload, envelopeMagic)
+}
+
+func (s Secret) envelopeCipher(salt []byte) (cipher.AEAD, error) {
+	secretKey, err := base64.StdEncoding.DecodeString(s.Base64SecretKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to decode secret key")
+	}
+
+	key, err := hkdf.Key(sha256.New, secretKey, salt, envelopeKDFInfo, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to derive encryption key")
 	}
 
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create cipher block")
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create GCM cipher")
+	}
+	return gcm, nil
+}
+
+// decryptLegacy decrypts payloads written before the envelope format, which
+// used unauthenticated AES-CFB keyed by the hex SHA-256 of the secret.
+func (s Secret) decryptLegacy(ciphertext []byte) (string, error) {
+	hashSHA, err := s.HashSHA()
+	if err != nil {
+		return "", err
+	}
+	key := []byte(hashSHA)[:32] // AES requires a key of 16, 24, or 32 bytes
+
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to create cipher block")
@@ -83,12 +227,18 @@ func (s Secret) Decrypt(encryptedMessage string) (string, error) {
 	}
 
 	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
+	plaintext := make([]byte, len(ciphertext)-aes.BlockSize)
 
 	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(ciphertext, ciphertext)
+	stream.XORKeyStream(plaintext, ciphertext[aes.BlockSize:])
+
+	// CFB cannot detect a wrong key, but the result of one is almost never
+	// valid UTF-8, which is the best signal available for legacy data.
+	if !utf8.Valid(plaintext) {
+		return "", ErrDecryptionFailed
+	}
 
-	return string(ciphertext), nil
+	return string(plaintext), nil
 }
 
 // NewSecret creates a new Secret from a base64 encoded secret key
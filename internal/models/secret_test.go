@@ -0,0 +1,176 @@
+package models
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"testing"
+
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSecret(t *testing.T, id int64) Secret {
+	t.Helper()
+	s, err := GenerateSecret()
+	require.NoError(t, err)
+	s.SecretKeyId = id
+	return s
+}
+
+// encryptLegacy reproduces the pre-envelope AES-CFB format.
+func encryptLegacy(t *testing.T, s Secret, message string) string {
+	t.Helper()
+	iv := make([]byte, aes.BlockSize)
+	_, err := io.ReadFull(rand.Reader, iv)
+	require.NoError(t, err)
+	return encryptLegacyWithIV(t, s, message, iv)
+}
+
+func encryptLegacyWithIV(t *testing.T, s Secret, message string, iv []byte) string {
+	t.Helper()
+	hashSHA, err := s.HashSHA()
+	require.NoError(t, err)
+	block, err := aes.NewCipher([]byte(hashSHA)[:32])
+	require.NoError(t, err)
+
+	ciphertext := make([]byte, aes.BlockSize+len(message))
+	copy(ciphertext, iv)
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext[aes.BlockSize:], []byte(message))
+	return base64.URLEncoding.EncodeToString(ciphertext)
+}
+
+func TestSecretEncryptDecrypt(t *testing.T) {
+	t.Run("round_trips_and_records_key_id", func(t *testing.T) {
+		s := testSecret(t, 42)
+
+		encrypted, err := s.Encrypt("KEY=value\n")
+		require.NoError(t, err)
+
+		keyId, ok := EncryptedSecretKeyID(encrypted)
+		assert.True(t, ok)
+		assert.Equal(t, int64(42), keyId)
+
+		decrypted, err := s.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "KEY=value\n", decrypted)
+	})
+
+	t.Run("fails_with_wrong_key", func(t *testing.T) {
+		encrypted, err := testSecret(t, 1).Encrypt("KEY=value")
+		require.NoError(t, err)
+
+		_, err = testSecret(t, 2).Decrypt(encrypted)
+		assert.True(t, errors.Is(err, ErrDecryptionFailed))
+		assert.Contains(t, err.Error(), "secret key 1")
+	})
+
+	t.Run("fails_when_tampered", func(t *testing.T) {
+		s := testSecret(t, 1)
+		encrypted, err := s.Encrypt("KEY=value")
+		require.NoError(t, err)
+
+		raw, err := base64.URLEncoding.DecodeString(encrypted)
+		require.NoError(t, err)
+		raw[len(raw)-1] ^= 0xff
+
+		_, err = s.Decrypt(base64.URLEncoding.EncodeToString(raw))
+		assert.True(t, errors.Is(err, ErrDecryptionFailed))
+	})
+
+	t.Run("rejects_unknown_version", func(t *testing.T) {
+		s := testSecret(t, 1)
+		encrypted, err := s.Encrypt("KEY=value")
+		require.NoError(t, err)
+
+		raw, err := base64.URLEncoding.DecodeString(encrypted)
+		require.NoError(t, err)
+		raw[len(envelopeMagic)] = 99
+
+		_, err = s.Decrypt(base64.URLEncoding.EncodeToString(raw))
+		assert.True(t, errors.Is(err, ErrUnsupportedEnvelope))
+	})
+
+	t.Run("derives_the_key_from_the_decoded_secret", func(t *testing.T) {
+		s := testSecret(t, 7)
+		encrypted, err := s.Encrypt("KEY=value")
+		require.NoError(t, err)
+
+		raw, err := base64.URLEncoding.DecodeString(encrypted)
+		require.NoError(t, err)
+		header := raw[:envelopeHeaderLen]
+		secretKey, err := base64.StdEncoding.DecodeString(s.Base64SecretKey)
+		require.NoError(t, err)
+		key, err := hkdf.Key(sha256.New, secretKey, header[envelopeHeaderLen-envelopeSaltLen:], envelopeKDFInfo, 32)
+		require.NoError(t, err)
+		block, err := aes.NewCipher(key)
+		require.NoError(t, err)
+		gcm, err := cipher.NewGCM(block)
+		require.NoError(t, err)
+
+		rest := raw[envelopeHeaderLen:]
+		plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], header)
+		require.NoError(t, err)
+		assert.Equal(t, "KEY=value", string(plaintext))
+	})
+
+	t.Run("rejects_a_secret_that_is_not_base64", func(t *testing.T) {
+		_, err := Secret{SecretKeyId: 1, Base64SecretKey: "not base64!"}.Encrypt("KEY=value")
+		assert.ErrorContains(t, err, "Failed to decode secret key")
+	})
+
+	t.Run("decrypts_legacy_payloads", func(t *testing.T) {
+		s := testSecret(t, 1)
+		legacy := encryptLegacy(t, s, "KEY=value")
+
+		_, ok := EncryptedSecretKeyID(legacy)
+		assert.False(t, ok)
+
+		decrypted, err := s.Decrypt(legacy)
+		require.NoError(t, err)
+		assert.Equal(t, "KEY=value", decrypted)
+	})
+
+	t.Run("decrypts_legacy_payloads_that_look_like_envelopes", func(t *testing.T) {
+		s := testSecret(t, 1)
+		iv := append(bytes.Clone(envelopeMagic), bytes.Repeat([]byte{envelopeVersion}, aes.BlockSize-len(envelopeMagic))...)
+		legacy := encryptLegacyWithIV(t, s, "KEY=a-value-long-enough-to-fill-an-envelope-header-and-more", iv)
+
+		decrypted, err := s.Decrypt(legacy)
+		require.NoError(t, err)
+		assert.Equal(t, "KEY=a-value-long-enough-to-fill-an-envelope-header-and-more", decrypted)
+	})
+
+	t.Run("checks_the_version_before_resolving_the_key", func(t *testing.T) {
+		resolved := false
+		prev := keyResolver
+		SetKeyResolver(func(Secret, int64) (Secret, bool) {
+			resolved = true
+			return Secret{}, false
+		})
+		t.Cleanup(func() { keyResolver = prev })
+
+		encrypted, err := testSecret(t, 1).Encrypt("KEY=value")
+		require.NoError(t, err)
+		raw, err := base64.URLEncoding.DecodeString(encrypted)
+		require.NoError(t, err)
+		raw[len(envelopeMagic)] = 99
+
+		_, err = testSecret(t, 2).Decrypt(base64.URLEncoding.EncodeToString(raw))
+		assert.True(t, errors.Is(err, ErrUnsupportedEnvelope))
+		assert.False(t, resolved)
+	})
+
+	t.Run("detects_wrong_key_on_legacy_payloads", func(t *testing.T) {
+		legacy := encryptLegacy(t, testSecret(t, 1), "KEY=value-long-enough-to-be-garbled")
+
+		_, err := testSecret(t, 2).Decrypt(legacy)
+		assert.True(t, errors.Is(err, ErrDecryptionFailed))
+	})
+}
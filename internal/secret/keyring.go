@@ -0,0 +1,297 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/pkg/cprint"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/fsutil"
+)
+
+// KeyringFile keeps the secret keys replaced by key rotations, in the global
+// directory, so versions encrypted before a rotation can still be decrypted.
+var KeyringFile = ".hxkeyring"
+
+type keyring struct {
+	Keys []keyringEntry `json:"keys"`
+}
+
+// keyringEntry is an earlier key of a project. Keys of projects whose .hxkey
+// is locked are sealed with the same passphrase; only the id is in the clear.
+type keyringEntry struct {
+	models.Secret
+	Sealed    json.RawMessage `json:"sealed,omitempty"`
+	ProjectId string          `json:"project_id"`
+	Retired   time.Time       `json:"retired"`
+}
+
+var (
+	keyringMu     sync.Mutex
+	keyringLoaded bool
+	keyringCache  keyring
+
+	// keyProjects maps the key material of each current key loaded or saved
+	// in this invocation to its project, so earlier keys are only looked up
+	// among that project's.
+	keyProjects = map[string]string{}
+)
+
+func init() {
+	models.SetKeyResolver(resolveEarlierKey)
+}
+
+// rememberProject records the project a current secret key belongs to.
+func rememberProject(projectId string, secret models.Secret) {
+	if secret.Base64SecretKey == "" {
+		return
+	}
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	keyProjects[secret.Base64SecretKey] = projectId
+}
+
+func resolveEarlierKey(current models.Secret, secretKeyId int64) (models.Secret, bool) {
+	keyringMu.Lock()
+	projectId, known := keyProjects[current.Base64SecretKey]
+	keyringMu.Unlock()
+
+	if known {
+		return LookupKeyring(projectId, secretKeyId)
+	}
+	// Without a project only an unambiguous id is trusted: key ids are
+	// creation times, so two projects can share one.
+	return lookupKeyring(func(k keyringEntry) bool { return true }, secretKeyId)
+}
+
+func keyringPath() (string, error) {
+	dir, err := GetGlobalDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, KeyringFile), nil
+}
+
+func readKeyring() (keyring, error) {
+	path, err := keyringPath()
+	if err != nil {
+		return keyring{}, err
+	}
+	data, err := FS.ReadFile(path)
+	if os.IsNotExist(err) {
+		return keyring{}, nil
+	}
+	if err != nil {
+		return keyring{}, errors.Wrapf(err, "Failed to read %s", KeyringFile)
+	}
+
+	var kr keyring
+	if err := json.Unmarshal(data, &kr); err != nil {
+		return keyring{}, errors.Wrapf(err, "Error decoding JSON file: %s", KeyringFile)
+	}
+	return kr, nil
+}
+
+// writeKeyring replaces the keyring atomically, so a crash never loses the
+// keys in it. It must be called within withKeyringLock.
+func writeKeyring(kr keyring) error {
+	data, err := json.MarshalIndent(kr, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Error encoding JSON")
+	}
+	path, err := keyringPath()
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFileAtomic(path, data, 0600); err != nil {
+		return errors.Wrapf(err, "Error writing file: %s", KeyringFile)
+	}
+
+	keyringCache = kr
+	keyringLoaded = true
+	return nil
+}
+
+// withKeyringLock runs fn holding keyringMu and an exclusive lock on the
+// keyring file, so concurrent hx processes rotating keys don't drop each
+// other's entries.
+func withKeyringLock(fn func() error) error {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	path, err := keyringPath()
+	if err != nil {
+		return err
+	}
+	if err := FS.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "Failed to create global directory")
+	}
+	unlock, err := fsutil.LockFile(path + ".lock")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to lock %s", KeyringFile)
+	}
+	defer unlock()
+
+	return fn()
+}
+
+// AddToKeyring records a secret key of a project that is being replaced, so
+// data encrypted with it stays readable. The key is sealed with the
+// passphrase when the project's .hxkey is locked. Adding a key twice is a
+// no-op.
+func AddToKeyring(projectId string, secret models.Secret) error {
+	entry := keyringEntry{Secret: secret, ProjectId: projectId, Retired: time.Now().UTC()}
+	if localKeyLocked() {
+		p, err := passphrase()
+		if err != nil {
+			return err
+		}
+		if err := entry.seal(p); err != nil {
+			return err
+		}
+	}
+
+	return withKeyringLock(func() error {
+		kr, err := readKeyring()
+		if err != nil {
+			return err
+		}
+		for _, k := range kr.Keys {
+			if k.ProjectId == projectId && k.SecretKeyId == secret.SecretKeyId {
+				return nil
+			}
+		}
+		kr.Keys = append(kr.Keys, entry)
+		return writeKeyring(kr)
+	})
+}
+
+// LookupKeyring returns the earlier secret key of a project with the given
+// id, if the keyring has it. Sealed keys are opened with the passphrase.
+func LookupKeyring(projectId string, secretKeyId int64) (models.Secret, bool) {
+	return lookupKeyring(func(k keyringEntry) bool { return k.ProjectId == projectId }, secretKeyId)
+}
+
+// lookupKeyring returns the key with the given id among the entries match
+// selects, as long as exactly one has it.
+func lookupKeyring(match func(keyringEntry) bool, secretKeyId int64) (models.Secret, bool) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	if !keyringLoaded {
+		kr, err := readKeyring()
+		if err != nil {
+			return models.Secret{}, false
+		}
+		keyringCache = kr
+		keyringLoaded = true
+	}
+
+	var found []keyringEntry
+	for _, k := range keyringCache.Keys {
+		if k.SecretKeyId == secretKeyId && match(k) {
+			found = append(found, k)
+		}
+	}
+
+	if len(found) != 1 {
+		return models.Secret{}, false
+	}
+	secret, err := found[0].open()
+	if err != nil {
+		cprint.Warning(fmt.Sprintf("Failed to open secret key %d in the keyring: %s", secretKeyId, err))
+		return models.Secret{}, false
+	}
+	return secret, true
+}
+
+// SealKeyring seals the plaintext keyring entries of a project with the
+// passphrase its .hxkey was just locked with. It returns how many were sealed.
+func SealKeyring(projectId, passphrase string) (int, error) {
+	return updateKeyring(projectId, func(k *keyringEntry) (bool, error) {
+		if k.Sealed != nil {
+			return false, nil
+		}
+		return true, k.seal(passphrase)
+	})
+}
+
+// UnsealKeyring stores the keyring entries of a project in plain text again,
+// after its .hxkey was unlocked. It returns how many were unsealed.
+func UnsealKeyring(projectId string) (int, error) {
+	return updateKeyring(projectId, func(k *keyringEntry) (bool, error) {
+		if k.Sealed == nil {
+			return false, nil
+		}
+		secret, err := k.open()
+		if err != nil {
+			return false, err
+		}
+		k.Secret, k.Sealed = secret, nil
+		return true, nil
+	})
+}
+
+func updateKeyring(projectId string, update func(*keyringEntry) (bool, error)) (int, error) {
+	count := 0
+	err := withKeyringLock(func() error {
+		kr, err := readKeyring()
+		if err != nil {
+			return err
+		}
+		for i := range kr.Keys {
+			if kr.Keys[i].ProjectId != projectId {
+				continue
+			}
+			changed, err := update(&kr.Keys[i])
+			if err != nil {
+				return errors.Wrapf(err, "Failed to update secret key %d in the keyring", kr.Keys[i].SecretKeyId)
+			}
+			if changed {
+				count++
+			}
+		}
+		if count == 0 {
+			return nil
+		}
+		return writeKeyring(kr)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (k *keyringEntry) seal(passphrase string) error {
+	sealed, err := LockSecret(k.Secret, passphrase)
+	if err != nil {
+		return err
+	}
+	k.Sealed = sealed
+	k.Base64SecretKey = ""
+	return nil
+}
+
+func (k keyringEntry) open() (models.Secret, error) {
+	if k.Sealed == nil {
+		return k.Secret, nil
+	}
+	var secret models.Secret
+	err := withPassphrase(func(p string) error {
+		var err error
+		secret, err = UnlockSecret(k.Sealed, p)
+		return err
+	})
+	return secret, err
+}
+
+// localKeyLocked reports whether the project's .hxkey is passphrase
+// protected.
+func localKeyLocked() bool {
+	data, err := FS.ReadFile(ManifestSecretFile)
+	return err == nil && IsLocked(data)
+}
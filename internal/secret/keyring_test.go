@@ -0,0 +1,196 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Hyphen/cli/internal/config"
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func withTempKeyring(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	keyringLoaded = false
+	t.Cleanup(func() {
+		keyringLoaded = false
+		keyProjects = map[string]string{}
+	})
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(config.ConfigDirEnv, "")
+	t.Setenv(config.ContextEnv, "")
+	dir, err := GetGlobalDirectory()
+	assert.NoError(t, err)
+	return filepath.Join(dir, KeyringFile)
+}
+
+func TestKeyring(t *testing.T) {
+	path := withTempKeyring(t)
+	old, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	old.SecretKeyId = 42
+
+	_, found := LookupKeyring("project-1", 42)
+	assert.False(t, found)
+
+	assert.NoError(t, AddToKeyring("project-1", old))
+	assert.NoError(t, AddToKeyring("project-1", old))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	keyringLoaded = false
+	key, found := LookupKeyring("project-1", 42)
+	assert.True(t, found)
+	assert.Equal(t, old, key)
+	assert.Len(t, keyringCache.Keys, 1)
+
+	_, found = LookupKeyring("project-2", 42)
+	assert.False(t, found)
+}
+
+func TestKeyringIsScopedByProject(t *testing.T) {
+	withTempKeyring(t)
+	one, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	two, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	one.SecretKeyId, two.SecretKeyId = 7, 7
+
+	assert.NoError(t, AddToKeyring("project-1", one))
+	assert.NoError(t, AddToKeyring("project-2", two))
+
+	key, found := LookupKeyring("project-2", 7)
+	assert.True(t, found)
+	assert.Equal(t, two, key)
+
+	current, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	encrypted, err := two.Encrypt("A=2")
+	assert.NoError(t, err)
+
+	// A key of unknown project can't tell the two apart.
+	_, err = current.Decrypt(encrypted)
+	assert.Error(t, err)
+
+	rememberProject("project-2", current)
+	decrypted, err := current.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "A=2", decrypted)
+}
+
+func TestKeyringIsScopedByContext(t *testing.T) {
+	defaultPath := withTempKeyring(t)
+	assert.NoError(t, config.CreateContext("client", config.Config{}))
+	t.Setenv(config.ContextEnv, "client")
+
+	old, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	old.SecretKeyId = 9
+	assert.NoError(t, AddToKeyring("project-1", old))
+
+	dir, err := GetGlobalDirectory()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(filepath.Dir(defaultPath), config.ContextsDirectory, "client"), dir)
+	_, err = os.Stat(filepath.Join(dir, KeyringFile))
+	assert.NoError(t, err)
+	_, err = os.Stat(defaultPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestKeyringIsSealedForLockedKeys(t *testing.T) {
+	withLocalKey(t)
+	path := withTempKeyring(t)
+	_, err := LockLocalSecret("correct horse")
+	assert.NoError(t, err)
+
+	old, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	old.SecretKeyId = 3
+	assert.NoError(t, AddToKeyring("project-1", old))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), old.Base64SecretKey)
+
+	keyringLoaded = false
+	key, found := LookupKeyring("project-1", 3)
+	assert.True(t, found)
+	assert.Equal(t, old, key)
+
+	count, err := UnsealKeyring("project-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), old.Base64SecretKey)
+
+	count, err = SealKeyring("project-1", "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), old.Base64SecretKey)
+}
+
+func TestSealedKeyringPromptsOnceForConcurrentLookups(t *testing.T) {
+	withLocalKey(t)
+	withTempKeyring(t)
+	_, err := LockLocalSecret("correct horse")
+	assert.NoError(t, err)
+
+	old, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	old.SecretKeyId = 5
+	assert.NoError(t, AddToKeyring("project-1", old))
+
+	var prompts atomic.Int32
+	prev := ReadPassphrase
+	ReadPassphrase = func(string) (string, error) {
+		prompts.Add(1)
+		return "correct horse", nil
+	}
+	t.Cleanup(func() { ReadPassphrase = prev })
+	cachedPassphrase = ""
+	keyringLoaded = false
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, found := LookupKeyring("project-1", 5)
+			assert.True(t, found)
+			assert.Equal(t, old, key)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), prompts.Load())
+}
+
+func TestDecryptUsesKeyring(t *testing.T) {
+	withTempKeyring(t)
+	old, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	old.SecretKeyId = 1
+	current, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	current.SecretKeyId = 2
+
+	encrypted, err := old.Encrypt("A=1")
+	assert.NoError(t, err)
+
+	_, err = current.Decrypt(encrypted)
+	assert.Error(t, err)
+
+	assert.NoError(t, AddToKeyring("project-1", old))
+	decrypted, err := current.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "A=1", decrypted)
+}
@@ -0,0 +1,397 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+// PassphraseEnv names the environment variable a locked .hxkey passphrase is
+// read from before falling back to a prompt.
+const PassphraseEnv = "HX_KEY_PASSPHRASE"
+
+const lockedKeyKDF = "argon2id"
+
+// ErrWrongPassphrase is returned when a locked key can't be opened with the
+// passphrase given.
+var ErrWrongPassphrase = errors.New("Wrong passphrase for the locked secret key")
+
+// lockedKey is the on-disk form of a passphrase-protected secret key. The key
+// is sealed with AES-GCM under a key derived from the passphrase with
+// argon2id; the secret key id is authenticated alongside it.
+type lockedKey struct {
+	SecretKeyId        int64     `json:"secret_key_id"`
+	KDF                kdfParams `json:"kdf"`
+	Nonce              string    `json:"nonce"`
+	EncryptedSecretKey string    `json:"encrypted_secret_key"`
+}
+
+type kdfParams struct {
+	Name    string `json:"name"`
+	Salt    string `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// defaultKDF follows the argon2id recommendation of RFC 9106 for
+// memory-constrained environments: 64 MiB, 3 passes.
+var defaultKDF = kdfParams{Name: lockedKeyKDF, Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// ReadPassphrase asks for the passphrase of a locked key. It is a variable so
+// tests and commands can replace the terminal prompt.
+var ReadPassphrase = promptPassphrase
+
+var (
+	// passphraseMu guards cachedPassphrase and serializes prompts: keys are
+	// opened from concurrent pulls, which must share one prompt.
+	passphraseMu sync.Mutex
+
+	// cachedPassphrase holds the last passphrase that opened a key, so a
+	// command that reads several key files only asks once.
+	cachedPassphrase string
+)
+
+// ConfirmLock asks whether to lock a plaintext key now. It is a variable so
+// tests can replace the terminal prompt.
+var ConfirmLock = promptConfirmLock
+
+// warningOutput is where warnings about plaintext keys are written.
+var warningOutput io.Writer = os.Stderr
+
+func promptPassphrase(message string) (string, error) {
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf("the secret key is locked; set %s to unlock it", PassphraseEnv)
+	}
+	// The prompt goes to stderr so it never ends up in output that is piped
+	// or captured, such as `hx env export | kubectl apply -f -`.
+	fmt.Fprint(os.Stderr, message)
+	passphrase, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// promptConfirmLock asks on stderr, like promptPassphrase, and fails when
+// there is no terminal to answer from or --no was given.
+func promptConfirmLock(message string) (bool, error) {
+	if flags.NoFlag || !term.IsTerminal(int(syscall.Stdin)) {
+		return false, fmt.Errorf("no terminal to confirm from")
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", message)
+	var response string
+	fmt.Scanln(&response)
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// passphrase returns the passphrase for locked keys from HX_KEY_PASSPHRASE,
+// the cache, or a prompt, in that order.
+func passphrase() (string, error) {
+	passphraseMu.Lock()
+	defer passphraseMu.Unlock()
+	return currentPassphrase()
+}
+
+// withPassphrase calls open with the passphrase for locked keys, caching it
+// when open succeeds and forgetting it when it fails. Concurrent callers wait
+// for each other, so a wrong passphrase is only asked for again once.
+func withPassphrase(open func(passphrase string) error) error {
+	passphraseMu.Lock()
+	defer passphraseMu.Unlock()
+
+	p, err := currentPassphrase()
+	if err != nil {
+		return err
+	}
+	if err := open(p); err != nil {
+		cachedPassphrase = ""
+		return err
+	}
+	cachedPassphrase = p
+	return nil
+}
+
+// currentPassphrase is passphrase without the lock, which callers hold.
+func currentPassphrase() (string, error) {
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return p, nil
+	}
+	if cachedPassphrase != "" {
+		return cachedPassphrase, nil
+	}
+	return ReadPassphrase(fmt.Sprintf("Passphrase for %s: ", ManifestSecretFile))
+}
+
+// IsLocked reports whether the contents of a key file are passphrase
+// protected.
+func IsLocked(data []byte) bool {
+	var probe struct {
+		EncryptedSecretKey string `json:"encrypted_secret_key"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.EncryptedSecretKey != ""
+}
+
+// LockSecret encrypts a secret key with a passphrase and returns the contents
+// of the locked key file.
+func LockSecret(secret models.Secret, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase can't be empty")
+	}
+
+	params := defaultKDF
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate salt")
+	}
+	params.Salt = base64.StdEncoding.EncodeToString(salt)
+
+	gcm, err := params.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "Failed to generate nonce")
+	}
+
+	sealed := gcm.Seal(nil, nonce, []byte(secret.Base64SecretKey), keyIdData(secret.SecretKeyId))
+	data, err := json.MarshalIndent(lockedKey{
+		SecretKeyId:        secret.SecretKeyId,
+		KDF:                params,
+		Nonce:              base64.StdEncoding.EncodeToString(nonce),
+		EncryptedSecretKey: base64.StdEncoding.EncodeToString(sealed),
+	}, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error encoding JSON")
+	}
+	return data, nil
+}
+
+// UnlockSecret decrypts the contents of a locked key file.
+func UnlockSecret(data []byte, passphrase string) (models.Secret, error) {
+	var lk lockedKey
+	if err := json.Unmarshal(data, &lk); err != nil {
+		return models.Secret{}, errors.Wrap(err, "Error decoding locked secret key")
+	}
+	if lk.KDF.Name != lockedKeyKDF {
+		return models.Secret{}, fmt.Errorf("locked secret key uses unsupported key derivation '%s'. Please update the Hyphen CLI", lk.KDF.Name)
+	}
+
+	gcm, err := lk.KDF.cipher(passphrase)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(lk.Nonce)
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return models.Secret{}, fmt.Errorf("locked secret key has an invalid nonce")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(lk.EncryptedSecretKey)
+	if err != nil {
+		return models.Secret{}, errors.Wrap(err, "Error decoding locked secret key")
+	}
+
+	key, err := gcm.Open(nil, nonce, sealed, keyIdData(lk.SecretKeyId))
+	if err != nil {
+		return models.Secret{}, ErrWrongPassphrase
+	}
+
+	secret := models.NewSecret(string(key))
+	secret.SecretKeyId = lk.SecretKeyId
+	return secret, nil
+}
+
+func (p kdfParams) cipher(passphrase string) (cipher.AEAD, error) {
+	salt, err := base64.StdEncoding.DecodeString(p.Salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error decoding key derivation salt")
+	}
+	key := argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, 32)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create cipher block")
+	}
+	return cipher.NewGCM(block)
+}
+
+func keyIdData(secretKeyId int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(secretKeyId))
+}
+
+// readSecretFile reads a key file, unlocking it if it is passphrase
+// protected.
+func readSecretFile(filename string) (models.Secret, error) {
+	data, err := FS.ReadFile(filename)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	if !IsLocked(data) {
+		var secret models.Secret
+		if err := json.Unmarshal(data, &secret); err != nil {
+			return models.Secret{}, errors.Wrapf(err, "Error decoding JSON file: %s, error: %v", filename, err)
+		}
+		return secret, nil
+	}
+
+	var secret models.Secret
+	err = withPassphrase(func(p string) error {
+		secret, err = UnlockSecret(data, p)
+		return err
+	})
+	if err != nil {
+		return models.Secret{}, errors.Wrapf(err, "Failed to unlock %s", filename)
+	}
+	return secret, nil
+}
+
+var (
+	// plaintextMu guards plaintextWarned, as keys can be loaded concurrently.
+	plaintextMu sync.Mutex
+
+	// plaintextWarned records that warnIfPlaintext already ran in this
+	// invocation.
+	plaintextWarned bool
+)
+
+// warnIfPlaintext offers to lock the project's .hxkey when it is stored in
+// plain text, once per invocation. Without a terminal to answer from it
+// warns instead. Both go to stderr, like the passphrase prompt, so they never
+// end up in the output of a command.
+func warnIfPlaintext(projectId string) {
+	plaintextMu.Lock()
+	defer plaintextMu.Unlock()
+	if plaintextWarned {
+		return
+	}
+	data, err := FS.ReadFile(ManifestSecretFile)
+	if err != nil || IsLocked(data) {
+		return
+	}
+	plaintextWarned = true
+
+	message := fmt.Sprintf("%s is stored in plain text.", ManifestSecretFile)
+	if info, err := FS.Stat(ManifestSecretFile); err == nil && info.Mode().Perm()&0077 != 0 {
+		message = fmt.Sprintf("%s is stored in plain text and readable by other users.", ManifestSecretFile)
+	}
+	confirmed, err := ConfirmLock(message + " Protect it with a passphrase now?")
+	if err != nil {
+		fmt.Fprintf(warningOutput, "Warning: %s Run `hx env key lock` to protect it with a passphrase.\n", message)
+		return
+	}
+	if !confirmed {
+		return
+	}
+	if err := lockLocalSecretInteractively(projectId); err != nil {
+		fmt.Fprintf(warningOutput, "Warning: %s was not locked: %s\n", ManifestSecretFile, err)
+		return
+	}
+	fmt.Fprintf(warningOutput, "Locked %s. Set %s to use the key non-interactively.\n", ManifestSecretFile, PassphraseEnv)
+}
+
+// lockLocalSecretInteractively locks the project's .hxkey, and seals its
+// earlier keys in the keyring, with a passphrase from HX_KEY_PASSPHRASE or
+// asked for twice.
+func lockLocalSecretInteractively(projectId string) error {
+	p := os.Getenv(PassphraseEnv)
+	if p == "" {
+		var err error
+		if p, err = ReadPassphrase("New passphrase: "); err != nil {
+			return err
+		}
+		confirmation, err := ReadPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if p != confirmation {
+			return fmt.Errorf("passphrases don't match")
+		}
+	}
+	if _, err := LockLocalSecret(p); err != nil {
+		return err
+	}
+	_, err := SealKeyring(projectId, p)
+	return err
+}
+
+// LockLocalSecret protects the project's .hxkey with a passphrase.
+func LockLocalSecret(passphrase string) (models.Secret, error) {
+	data, err := FS.ReadFile(ManifestSecretFile)
+	if err != nil {
+		return models.Secret{}, errors.Wrapf(err, "Failed to read %s", ManifestSecretFile)
+	}
+	if IsLocked(data) {
+		return models.Secret{}, fmt.Errorf("%s is already locked", ManifestSecretFile)
+	}
+
+	secret, err := readSecretFile(ManifestSecretFile)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	locked, err := LockSecret(secret, passphrase)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	if err := writeKeyFile(locked); err != nil {
+		return models.Secret{}, err
+	}
+	passphraseMu.Lock()
+	cachedPassphrase = passphrase
+	passphraseMu.Unlock()
+	return secret, nil
+}
+
+// UnlockLocalSecret removes the passphrase from the project's .hxkey, storing
+// the key in plain text again.
+func UnlockLocalSecret() (models.Secret, error) {
+	data, err := FS.ReadFile(ManifestSecretFile)
+	if err != nil {
+		return models.Secret{}, errors.Wrapf(err, "Failed to read %s", ManifestSecretFile)
+	}
+	if !IsLocked(data) {
+		return models.Secret{}, fmt.Errorf("%s is not locked", ManifestSecretFile)
+	}
+
+	secret, err := readSecretFile(ManifestSecretFile)
+	if err != nil {
+		return models.Secret{}, err
+	}
+	jsonData, err := json.MarshalIndent(secret, "", "  ")
+	if err != nil {
+		return models.Secret{}, errors.Wrap(err, "Error encoding JSON")
+	}
+	return secret, writeKeyFile(jsonData)
+}
+
+// writeKeyFile replaces the project's .hxkey. WriteFile keeps the mode of an
+// existing file, so the mode is tightened explicitly.
+func writeKeyFile(data []byte) error {
+	if err := FS.WriteFile(ManifestSecretFile, data, 0600); err != nil {
+		return errors.Wrapf(err, "Error writing file: %s", ManifestSecretFile)
+	}
+	if err := os.Chmod(ManifestSecretFile, 0600); err != nil {
+		return errors.Wrapf(err, "Failed to restrict permissions of %s", ManifestSecretFile)
+	}
+	return nil
+}
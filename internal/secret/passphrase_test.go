@@ -0,0 +1,200 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Hyphen/cli/internal/models"
+	"github.com/Hyphen/cli/pkg/errors"
+	"github.com/Hyphen/cli/pkg/flags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockSecret(t *testing.T) {
+	s, err := models.GenerateSecret()
+	assert.NoError(t, err)
+
+	locked, err := LockSecret(s, "correct horse")
+	assert.NoError(t, err)
+	assert.True(t, IsLocked(locked))
+	assert.NotContains(t, string(locked), s.Base64SecretKey)
+
+	unlocked, err := UnlockSecret(locked, "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, s, unlocked)
+
+	_, err = UnlockSecret(locked, "wrong horse")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+	_, err = LockSecret(s, "")
+	assert.Error(t, err)
+}
+
+// withLocalKey runs the test in a directory holding a plaintext .hxkey, with
+// no global key and no passphrase cached or in the environment.
+func withLocalKey(t *testing.T) models.Secret {
+	t.Helper()
+	withMockVinz(t, &mockVinz{getErr: errors.Wrapf(errors.ErrNotFound, "not found")})
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "")
+	flags.LocalSecret = true
+	cachedPassphrase = ""
+	plaintextWarned, warningOutput = false, io.Discard
+	ConfirmLock = func(string) (bool, error) { return false, fmt.Errorf("no terminal") }
+	t.Cleanup(func() {
+		cachedPassphrase = ""
+		plaintextWarned, warningOutput = false, os.Stderr
+		ConfirmLock = promptConfirmLock
+	})
+
+	s, err := InitializeSecret("org_test", "proj_test", SecretLocationLocal, ManifestSecretFile)
+	assert.NoError(t, err)
+	return s
+}
+
+func TestWarnIfPlaintext(t *testing.T) {
+	withLocalKey(t)
+	var out bytes.Buffer
+	warningOutput = &out
+
+	warnIfPlaintext("proj_test")
+	warnIfPlaintext("proj_test")
+	assert.Equal(t, 1, strings.Count(out.String(), "is stored in plain text."))
+
+	plaintextWarned = false
+	out.Reset()
+	_, err := LockLocalSecret("correct horse")
+	assert.NoError(t, err)
+	warnIfPlaintext("proj_test")
+	assert.Empty(t, out.String())
+}
+
+func TestWarnIfPlaintextOffersToLock(t *testing.T) {
+	s := withLocalKey(t)
+	withTempKeyring(t)
+	old, err := models.GenerateSecret()
+	assert.NoError(t, err)
+	old.SecretKeyId = 4
+	assert.NoError(t, AddToKeyring("proj_test", old))
+
+	ConfirmLock = func(string) (bool, error) { return false, nil }
+	warnIfPlaintext("proj_test")
+	data, err := os.ReadFile(ManifestSecretFile)
+	assert.NoError(t, err)
+	assert.False(t, IsLocked(data))
+
+	plaintextWarned = false
+	ConfirmLock = func(string) (bool, error) { return true, nil }
+	prev := ReadPassphrase
+	ReadPassphrase = func(string) (string, error) { return "correct horse", nil }
+	t.Cleanup(func() { ReadPassphrase = prev })
+	warnIfPlaintext("proj_test")
+
+	data, err = os.ReadFile(ManifestSecretFile)
+	assert.NoError(t, err)
+	unlocked, err := UnlockSecret(data, "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, s, unlocked)
+
+	keyring, err := readKeyring()
+	assert.NoError(t, err)
+	assert.Len(t, keyring.Keys, 1)
+	assert.NotNil(t, keyring.Keys[0].Sealed)
+}
+
+func TestLockLocalSecret(t *testing.T) {
+	t.Run("locked_key_loads_with_passphrase", func(t *testing.T) {
+		s := withLocalKey(t)
+		info, err := os.Stat(ManifestSecretFile)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		_, err = LockLocalSecret("correct horse")
+		assert.NoError(t, err)
+		_, err = LockLocalSecret("correct horse")
+		assert.ErrorContains(t, err, "already locked")
+
+		cachedPassphrase = ""
+		t.Setenv(PassphraseEnv, "correct horse")
+		loaded, location, err := LoadSecret("org_test", "proj_test")
+		assert.NoError(t, err)
+		assert.Equal(t, SecretLocationLocal, location)
+		assert.Equal(t, s, loaded)
+
+		unlocked, err := UnlockLocalSecret()
+		assert.NoError(t, err)
+		assert.Equal(t, s, unlocked)
+		data, err := os.ReadFile(ManifestSecretFile)
+		assert.NoError(t, err)
+		assert.False(t, IsLocked(data))
+	})
+
+	t.Run("wrong_passphrase_never_replaces_the_key", func(t *testing.T) {
+		withLocalKey(t)
+		_, err := LockLocalSecret("correct horse")
+		assert.NoError(t, err)
+		before, err := os.ReadFile(ManifestSecretFile)
+		assert.NoError(t, err)
+
+		cachedPassphrase = ""
+		t.Setenv(PassphraseEnv, "wrong horse")
+		_, _, err = LoadOrInitializeSecret("org_test", "proj_test")
+		assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+		after, err := os.ReadFile(ManifestSecretFile)
+		assert.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("wrong_passphrase_never_falls_back_to_a_parent_key", func(t *testing.T) {
+		withLocalKey(t)
+		parent, err := models.GenerateSecret()
+		assert.NoError(t, err)
+		data, err := json.Marshal(parent)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join("..", ManifestSecretFile), data, 0600))
+		t.Cleanup(func() { _ = os.Remove(filepath.Join("..", ManifestSecretFile)) })
+
+		_, err = LockLocalSecret("correct horse")
+		assert.NoError(t, err)
+
+		cachedPassphrase = ""
+		t.Setenv(PassphraseEnv, "wrong horse")
+		_, _, err = LoadSecret("org_test", "proj_test")
+		assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+		t.Setenv(PassphraseEnv, "")
+		prompts := 0
+		prev := ReadPassphrase
+		ReadPassphrase = func(string) (string, error) {
+			prompts++
+			return "wrong horse", nil
+		}
+		t.Cleanup(func() { ReadPassphrase = prev })
+		_, _, err = LoadSecret("org_test", "proj_test")
+		assert.ErrorIs(t, err, ErrWrongPassphrase)
+		assert.Equal(t, 1, prompts)
+	})
+
+	t.Run("rotation_keeps_the_key_locked", func(t *testing.T) {
+		withLocalKey(t)
+		_, err := LockLocalSecret("correct horse")
+		assert.NoError(t, err)
+
+		next, err := models.GenerateSecret()
+		assert.NoError(t, err)
+		assert.NoError(t, UpsertLocalSecret(next))
+
+		data, err := os.ReadFile(ManifestSecretFile)
+		assert.NoError(t, err)
+		unlocked, err := UnlockSecret(data, "correct horse")
+		assert.NoError(t, err)
+		assert.Equal(t, next, unlocked)
+	})
+}
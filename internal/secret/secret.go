@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"dario.cat/mergo"
+	"github.com/Hyphen/cli/internal/config"
 	"github.com/Hyphen/cli/internal/models"
 	"github.com/Hyphen/cli/internal/vinz"
 	"github.com/Hyphen/cli/pkg/cprint"
@@ -62,13 +63,21 @@ func LoadOrInitializeSecret(organizationId, projectIdOrAlternateId string) (mode
 
 	if flags.LocalSecret && location == SecretLocationVinz {
 		// warn
-		cprint.Warning("This project already has remotely configured secrets. Ignoring --localSecret flag.")
+		cprint.Warning("This project already has remotely configured secrets. Ignoring --localSecret flag. Run `hx env key migrate --to local` to move the key to a local .hxkey.")
 	}
 
 	return secret, location, err
 }
 
 func LoadSecret(organizationId, projectIdOrAlternateId string) (models.Secret, SecretLocation, error) {
+	secret, location, err := loadSecret(organizationId, projectIdOrAlternateId)
+	if err == nil {
+		rememberProject(projectIdOrAlternateId, secret)
+	}
+	return secret, location, err
+}
+
+func loadSecret(organizationId, projectIdOrAlternateId string) (models.Secret, SecretLocation, error) {
 	// Always default to looking in Vinz first, unless there is a LocalSecret flag.
 	if !flags.LocalSecret {
 		secret, err := getVinzService().GetKey(organizationId, projectIdOrAlternateId)
@@ -89,9 +98,13 @@ func LoadSecret(organizationId, projectIdOrAlternateId string) (models.Secret, S
 
 	if _, err := os.Stat(ManifestSecretFile); err == nil {
 		secret, err := restoreSecretFromFile(ManifestSecretFile)
-		if err == nil {
-			return secret, SecretLocationLocal, nil
+		if err != nil {
+			// As with Vinz, a key that can't be read (say, a wrong passphrase)
+			// must not be mistaken for a missing one and overwritten.
+			return models.Secret{}, SecretLocationNone, err
 		}
+		warnIfPlaintext(projectIdOrAlternateId)
+		return secret, SecretLocationLocal, nil
 	}
 
 	return models.Secret{}, SecretLocationNone, nil
@@ -102,6 +115,7 @@ func InitializeSecret(organizationId, projectIdOrAlternateId string, secretLocat
 	if err != nil {
 		return models.Secret{}, errors.Wrap(err, "Failed to create new secret key")
 	}
+	rememberProject(projectIdOrAlternateId, ms)
 
 	jsonData, err := json.MarshalIndent(ms, "", "  ")
 	if err != nil {
@@ -110,7 +124,13 @@ func InitializeSecret(organizationId, projectIdOrAlternateId string, secretLocat
 
 	switch secretLocation {
 	case SecretLocationLocal:
-		err = FS.WriteFile(secretFile, jsonData, 0644)
+		// The key is locked right away when a passphrase is provided.
+		if p := os.Getenv(PassphraseEnv); p != "" {
+			if jsonData, err = LockSecret(ms, p); err != nil {
+				return models.Secret{}, err
+			}
+		}
+		err = FS.WriteFile(secretFile, jsonData, 0600)
 		if err != nil {
 			return models.Secret{}, errors.Wrapf(err, "Error writing file: %s", secretFile)
 		}
@@ -134,13 +154,20 @@ func restoreSecretFromFile(manifestSecretFile string) (models.Secret, error) {
 	if err == nil {
 		return monorepoSecret, nil
 	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return models.Secret{}, err
+	}
 
 	var secret models.Secret
 	//var hasSecret bool
 
-	globalSecretFile := fmt.Sprintf("%s/%s", GetGlobalDirectory(), manifestSecretFile)
+	globalDir, err := GetGlobalDirectory()
+	if err != nil {
+		return models.Secret{}, err
+	}
+	globalSecretFile := filepath.Join(globalDir, manifestSecretFile)
 
-	globalSecret, err := readAndUnmarshalConfigJSON[models.Secret](globalSecretFile)
+	globalSecret, err := readSecretFile(globalSecretFile)
 	if err == nil {
 		secret = globalSecret
 		//hasSecret = true
@@ -148,7 +175,7 @@ func restoreSecretFromFile(manifestSecretFile string) (models.Secret, error) {
 		return models.Secret{}, err
 	}
 
-	localSecret, localSecretErr := readAndUnmarshalConfigJSON[models.Secret](manifestSecretFile)
+	localSecret, localSecretErr := readSecretFile(manifestSecretFile)
 	if localSecretErr == nil {
 		mergeErr := mergo.Merge(&secret, localSecret, mergo.WithOverride)
 		if mergeErr != nil {
@@ -196,77 +223,128 @@ func RestoreSecretFromMonorepo() (models.Secret, error) {
 
 		// Look for .hxkey in the same directory
 		secretPath := filepath.Join(currentDir, ManifestSecretFile)
-		secret, err := readAndUnmarshalConfigJSON[models.Secret](secretPath)
+		secret, err := readSecretFile(secretPath)
 		if err == nil {
 			return secret, nil
 		}
+		// A key that is here but can't be read, say because of a wrong
+		// passphrase, must not be skipped for another project's key above.
+		if !os.IsNotExist(err) {
+			return models.Secret{}, err
+		}
 
 		// Move up one directory
 		parentDir := filepath.Dir(currentDir)
 
 		// Check if we've hit the root directory
 		if parentDir == currentDir {
-			return models.Secret{}, errors.New("No monorepo configuration found in parent directories")
+			return models.Secret{}, fmt.Errorf("no monorepo configuration found in parent directories: %w", os.ErrNotExist)
 		}
 
 		currentDir = parentDir
 	}
 }
 
+// UpsertLocalSecret writes the secret key to the project's .hxkey, keeping it
+// locked with the same passphrase if the current file is locked. A new file
+// is locked when HX_KEY_PASSPHRASE is set.
 func UpsertLocalSecret(secret models.Secret) error {
-	localSecretFile := ManifestSecretFile
-
 	jsonData, err := json.MarshalIndent(secret, "", "  ")
 	if err != nil {
 		return errors.Wrap(err, "Error encoding JSON")
 	}
 
-	err = FS.WriteFile(localSecretFile, jsonData, 0644)
-	if err != nil {
-		return errors.Wrapf(err, "Error writing file: %s", localSecretFile)
+	current, err := FS.ReadFile(ManifestSecretFile)
+	if (err == nil && IsLocked(current)) || (os.IsNotExist(err) && os.Getenv(PassphraseEnv) != "") {
+		p, err := passphrase()
+		if err != nil {
+			return err
+		}
+		if jsonData, err = LockSecret(secret, p); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return writeKeyFile(jsonData)
 }
 
-func RotateSecret() (models.Secret, error) {
-	newSecret, err := models.GenerateSecret()
-	if err != nil {
-		return newSecret, errors.Wrap(err, "Failed to generate new secret key")
+// LoadSecretFrom loads the project's secret key from the given location only,
+// reporting false if there is no key there.
+func LoadSecretFrom(organizationId, projectIdOrAlternateId string, location SecretLocation) (models.Secret, bool, error) {
+	secret, found, err := loadSecretFrom(organizationId, projectIdOrAlternateId, location)
+	if found {
+		rememberProject(projectIdOrAlternateId, secret)
 	}
+	return secret, found, err
+}
 
-	organizationId, err := flags.GetOrganizationID()
-	if err != nil {
-		return newSecret, errors.Wrap(err, "Failed to get organization ID")
+func loadSecretFrom(organizationId, projectIdOrAlternateId string, location SecretLocation) (models.Secret, bool, error) {
+	switch location {
+	case SecretLocationVinz:
+		key, err := getVinzService().GetKey(organizationId, projectIdOrAlternateId)
+		if errors.Is(err, errors.ErrNotFound) {
+			return models.Secret{}, false, nil
+		}
+		if err != nil {
+			return models.Secret{}, false, errors.Wrap(err, "Failed to load secret")
+		}
+		return models.Secret{
+			SecretKeyId:     key.SecretKeyId,
+			Base64SecretKey: key.SecretKey,
+		}, true, nil
+	case SecretLocationLocal:
+		secret, err := readSecretFile(ManifestSecretFile)
+		if os.IsNotExist(err) {
+			return models.Secret{}, false, nil
+		}
+		if err != nil {
+			return models.Secret{}, false, err
+		}
+		return secret, true, nil
+	default:
+		return models.Secret{}, false, fmt.Errorf("specified secret location must be local or vinz")
 	}
+}
 
-	projectId, err := flags.GetProjectID()
-	if err != nil {
-		return newSecret, errors.Wrap(err, "Failed to get project ID")
+// ArchiveLocalSecret moves the project's .hxkey aside once the key is stored
+// elsewhere, or deletes it when remove is set. It returns the archive's path.
+func ArchiveLocalSecret(remove bool) (string, error) {
+	if remove {
+		if err := FS.Remove(ManifestSecretFile); err != nil {
+			return "", errors.Wrapf(err, "Failed to remove %s", ManifestSecretFile)
+		}
+		return "", nil
 	}
 
-	// If there's a secret in Vinz, we're using vinz for this project. Do not use local secret.
-	_, err = getVinzService().GetKey(organizationId, projectId)
-	if err == nil {
-		// rotate in vinz
-		_, err = getVinzService().SaveKey(organizationId, projectId, vinz.Key{
-			SecretKeyId: newSecret.SecretKeyId,
-			SecretKey:   newSecret.Base64(),
+	archive := ManifestSecretFile + ".migrated"
+	if err := os.Rename(ManifestSecretFile, archive); err != nil {
+		return "", errors.Wrapf(err, "Failed to archive %s", ManifestSecretFile)
+	}
+	return archive, nil
+}
+
+// SaveSecret replaces the project's secret key in the given location, which
+// is how a rotation switches to its new key.
+func SaveSecret(organizationId, projectIdOrAlternateId string, location SecretLocation, secret models.Secret) error {
+	rememberProject(projectIdOrAlternateId, secret)
+	switch location {
+	case SecretLocationVinz:
+		_, err := getVinzService().SaveKey(organizationId, projectIdOrAlternateId, vinz.Key{
+			SecretKeyId: secret.SecretKeyId,
+			SecretKey:   secret.Base64(),
 		})
 		if err != nil {
-			return newSecret, errors.Wrap(err, "Failed to save new key to Vinz")
+			return errors.Wrap(err, "Failed to save secret key to Vinz")
 		}
-
-		return newSecret, nil
-	}
-
-	// rotate locally
-	err = UpsertLocalSecret(newSecret)
-	if err != nil {
-		return newSecret, errors.Wrap(err, "Failed to save new secret locally")
+		return nil
+	case SecretLocationLocal:
+		if err := UpsertLocalSecret(secret); err != nil {
+			return errors.Wrap(err, "Failed to save secret key locally")
+		}
+		return nil
+	default:
+		return fmt.Errorf("specified secret location must be local or vinz")
 	}
-
-	return newSecret, nil
 }
 
 func readAndUnmarshalConfigJSON[T any](filename string) (T, error) {
@@ -285,11 +363,8 @@ func readAndUnmarshalConfigJSON[T any](filename string) (T, error) {
 	return result, nil
 }
 
-func GetGlobalDirectory() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Println("Error retrieving home directory:", err)
-		return ""
-	}
-	return home
+// GetGlobalDirectory returns the directory the global .hxkey and the keyring
+// of the active context are kept in, next to its global .hx.
+func GetGlobalDirectory() (string, error) {
+	return config.GlobalConfigDirectory()
 }
@@ -138,3 +138,38 @@ func TestLoadSecret_NotFoundReturnsNoneWithoutError(t *testing.T) {
 		t.Fatalf("expected SecretLocationNone, got %v", location)
 	}
 }
+
+// LoadSecretFrom only looks in the location it is given, so a migration can
+// tell a missing key apart from one stored elsewhere.
+func TestLoadSecretFrom(t *testing.T) {
+	mock := &mockVinz{getErr: errors.Wrapf(errors.ErrNotFound, "not found")}
+	withMockVinz(t, mock)
+	t.Setenv("HOME", t.TempDir())
+
+	if _, found, err := LoadSecretFrom("org_test", "proj_test", SecretLocationVinz); err != nil || found {
+		t.Fatalf("expected no key in Vinz, got found=%v err=%v", found, err)
+	}
+	if _, found, err := LoadSecretFrom("org_test", "proj_test", SecretLocationLocal); err != nil || found {
+		t.Fatalf("expected no local key, got found=%v err=%v", found, err)
+	}
+
+	local, err := InitializeSecret("org_test", "proj_test", SecretLocationLocal, ManifestSecretFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, found, err := LoadSecretFrom("org_test", "proj_test", SecretLocationLocal)
+	if err != nil || !found || loaded != local {
+		t.Fatalf("expected the local key, got %v found=%v err=%v", loaded, found, err)
+	}
+
+	archive, err := ArchiveLocalSecret(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Fatalf("expected the key to be archived: %v", err)
+	}
+	if _, found, _ := LoadSecretFrom("org_test", "proj_test", SecretLocationLocal); found {
+		t.Fatal("expected no local key after archiving")
+	}
+}
@@ -0,0 +1,50 @@
+// Package testutil holds the fixtures shared by the tests of several
+// packages.
+package testutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Hyphen/cli/internal/env"
+	"github.com/Hyphen/cli/internal/models"
+)
+
+// ChdirTemp runs the rest of the test in a new temporary directory, which it
+// returns, and restores the working directory afterwards.
+func ChdirTemp(t testing.TB) string {
+	t.Helper()
+
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to chdir to temp dir: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := os.Chdir(originalDir); err != nil {
+			t.Fatalf("failed to restore current directory: %v", err)
+		}
+	})
+
+	return dir
+}
+
+// EncryptedEnv returns version of a remote env holding content encrypted
+// with secret, as the env service returns it.
+func EncryptedEnv(t testing.TB, secret models.Secret, content string, version int) models.Env {
+	t.Helper()
+
+	e := env.NewFromContent(content)
+	e.Version = &version
+	data, err := e.EncryptData(secret)
+	if err != nil {
+		t.Fatalf("failed to encrypt env: %v", err)
+	}
+	e.Data = data
+	return e
+}
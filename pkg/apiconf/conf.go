@@ -3,10 +3,56 @@ package apiconf
 import (
 	"os"
 	"strings"
+	"sync"
 
+	"github.com/Hyphen/cli/internal/config"
 	"github.com/Hyphen/cli/pkg/flags"
 )
 
+var (
+	endpointsMu      sync.Mutex
+	endpointsContext string
+	endpoints        config.Endpoints
+)
+
+// LoadEndpoints reads the API base URLs configured for the active context.
+// It fails if the context doesn't exist or its configuration can't be read,
+// so requests never go to the default URLs by mistake. Only the global
+// configuration is consulted, so a project's .hx can never redirect requests
+// and their credentials elsewhere.
+func LoadEndpoints() error {
+	_, err := loadEndpoints()
+	return err
+}
+
+// loadEndpoints reads the endpoints once per context.
+func loadEndpoints() (config.Endpoints, error) {
+	endpointsMu.Lock()
+	defer endpointsMu.Unlock()
+
+	name := config.ActiveContext()
+	if endpointsContext == name {
+		return endpoints, nil
+	}
+	c, err := config.RestoreGlobalConfig()
+	if err != nil && !os.IsNotExist(err) {
+		return config.Endpoints{}, err
+	}
+	endpoints = config.Endpoints{}
+	if c.Endpoints != nil {
+		endpoints = *c.Endpoints
+	}
+	endpointsContext = name
+	return endpoints, nil
+}
+
+// contextEndpoints returns the endpoints of the active context. The root
+// command runs LoadEndpoints first, so an error here was already reported.
+func contextEndpoints() config.Endpoints {
+	e, _ := loadEndpoints()
+	return e
+}
+
 func GetBaseApixUrl() string {
 	if flags.DevFlag || strings.ToLower(os.Getenv("HYPHEN_DEV")) == "true" {
 		return "https://dev-api.hyphen.ai"
@@ -14,6 +60,9 @@ func GetBaseApixUrl() string {
 	if strings.ToLower(os.Getenv("HYPHEN_Local")) == "true" {
 		return "http://localhost:4000"
 	}
+	if url := contextEndpoints().Api; url != "" {
+		return url
+	}
 	return "https://api.hyphen.ai"
 }
 
@@ -24,6 +73,9 @@ func GetBaseHorizonUrl() string {
 	if strings.ToLower(os.Getenv("HYPHEN_Local")) == "true" {
 		return "http://localhost:3333"
 	}
+	if url := contextEndpoints().Horizon; url != "" {
+		return url
+	}
 	return "https://toggle.hyphen.cloud"
 }
 
@@ -34,6 +86,9 @@ func GetBaseAppUrl() string {
 	if strings.ToLower(os.Getenv("HYPHEN_Local")) == "true" {
 		return "http://localhost:3000"
 	}
+	if url := contextEndpoints().App; url != "" {
+		return url
+	}
 	return "https://app.hyphen.ai"
 }
 
@@ -41,6 +96,9 @@ func GetBaseAuthUrl() string {
 	if flags.DevFlag || strings.ToLower(os.Getenv("HYPHEN_DEV")) == "true" {
 		return "https://dev-auth.hyphen.ai"
 	}
+	if url := contextEndpoints().Auth; url != "" {
+		return url
+	}
 	return "https://auth.hyphen.ai"
 }
 
@@ -56,6 +114,9 @@ func GetBaseVinzUrl() string {
 		return "https://dev-vinz.hyphen.ai"
 		//return "http://localhost:3113"
 	}
+	if url := contextEndpoints().Vinz; url != "" {
+		return url
+	}
 	return "https://vinz.hyphen.ai"
 }
 
@@ -66,5 +127,8 @@ func GetIOBaseUrl() string {
 	if strings.ToLower(os.Getenv("HYPHEN_Local")) == "true" {
 		return "http://localhost:4000"
 	}
+	if url := contextEndpoints().Api; url != "" {
+		return url
+	}
 	return "https://api.hyphen.ai"
 }
@@ -97,6 +97,22 @@ func Is(err, target error) bool {
 	}
 }
 
+// As finds the first error in err's chain that matches target, like the
+// standard errors.As.
+func As(err error, target any) bool {
+	return errors.As(err, target)
+}
+
+// ExitError asks the CLI to exit with Code without printing an error, for
+// commands such as env run that pass through the exit status of a child.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
 func HandleHTTPError(resp *http.Response) *Error {
 	body, _ := io.ReadAll(resp.Body)
 	errorMessage := body
@@ -29,3 +29,12 @@ func TestHandleHTTPErrorIncludesRequestContext(t *testing.T) {
 		"bad request for POST http://localhost:4000/api/organizations/org-1/apps/app-1/dockerfile: invalid body",
 	)
 }
+
+func TestExitErrorThroughWrap(t *testing.T) {
+	wrapped := Wrap(&ExitError{Code: 3}, "child failed")
+
+	var exitErr *ExitError
+	assert.True(t, As(wrapped, &exitErr))
+	assert.Equal(t, 3, exitErr.Code)
+	assert.False(t, As(New("other"), &exitErr))
+}
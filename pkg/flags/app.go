@@ -1,6 +1,9 @@
 package flags
 
 import (
+	"fmt"
+	"path/filepath"
+
 	"github.com/Hyphen/cli/internal/config"
 	"github.com/Hyphen/cli/pkg/errors"
 )
@@ -21,3 +24,33 @@ func GetApplicationID() (string, error) {
 
 	return *manifest.AppId, nil
 }
+
+// GetProjectApplicationIDs returns the apps whose environments use the
+// project's secret key: the current app, or every app of a monorepo.
+func GetProjectApplicationIDs() ([]string, error) {
+	cfg, err := config.RestoreConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.IsMonorepoProject() || cfg.Project == nil {
+		appId, err := GetApplicationID()
+		if err != nil {
+			return nil, err
+		}
+		return []string{appId}, nil
+	}
+
+	var appIds []string
+	for _, appDir := range cfg.Project.Apps {
+		appCfg, err := config.RestoreConfigFromFile(filepath.Join(appDir, config.ManifestConfigFile))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read the configuration of app %s", appDir)
+		}
+		if appCfg.AppId == nil {
+			return nil, fmt.Errorf("app %s has no app ID configured", appDir)
+		}
+		appIds = append(appIds, *appCfg.AppId)
+	}
+	return appIds, nil
+}
@@ -2,6 +2,7 @@ package flags
 
 var (
 	ApplicationFlag   string
+	ContextFlag       string
 	DevFlag           bool
 	DockerfileFlag    string
 	EnvironmentFlag   string